@@ -5,16 +5,24 @@ import (
     "github.com/spf13/cobra"
 )
 
+var loginCheck bool
+
 var loginCmd = &cobra.Command{
     Use:   "login",
     Short: "Open ChatGPT to set up/login and grant clipboard permission",
+    Long:  "Open ChatGPT to set up/login and grant clipboard permission. The browser closes automatically once you are logged in and clipboard access is granted. With --check, only verify the login state and exit 0 (ready) or 1 (not ready).",
     RunE: func(cmd *cobra.Command, args []string) error {
+        cmd.SilenceUsage = true
         a := app.New()
+        a.Options.ForceUnlock = flagForceUnlock
+        if loginCheck {
+            return a.CheckLogin()
+        }
         return a.Login()
     },
 }
 
 func init() {
     rootCmd.AddCommand(loginCmd)
+    loginCmd.Flags().BoolVar(&loginCheck, "check", false, "Only verify login state and clipboard permission; exit 0 if ready, 1 otherwise")
 }
-
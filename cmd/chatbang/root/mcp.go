@@ -49,6 +49,7 @@ provider = "fs"
 # max_bytes = 1048576
 # include_hidden = false
 # allow_binary = false
+# allow_write = false
 `, mcpName, rootsLine)
 
         if err := os.MkdirAll(configDir, 0o755); err != nil {
@@ -11,11 +11,17 @@ import (
     "github.com/sirupsen/logrus"
     "github.com/spf13/cobra"
 
+    "gg/internal/templates"
     "gg/pkg/app"
 )
 
 var (
     flagConfigLogin bool
+    flagForceUnlock bool
+    flagStyle       string
+    flagTemplate    string
+    flagVars        []string
+    flagNoContext   bool
 )
 
 // rootCmd defines the base command for chatbang
@@ -36,6 +42,18 @@ var rootCmd = &cobra.Command{
             }
         }
         a := app.New()
+        a.Options.ForceUnlock = flagForceUnlock
+        a.Options.Style = flagStyle
+        a.Options.NoContext = flagNoContext
+        if flagTemplate != "" {
+            vars, err := templates.ParseVars(flagVars)
+            if err != nil {
+                return fmt.Errorf("--var: %w", err)
+            }
+            a.Options.Template, a.Options.Vars = flagTemplate, vars
+        } else if len(flagVars) > 0 {
+            return fmt.Errorf("--var needs --template")
+        }
         if flagConfigLogin {
             return a.Login()
         }
@@ -93,4 +111,9 @@ func Execute() {
 
 func init() {
     rootCmd.Flags().BoolVar(&flagConfigLogin, "config", false, "Open ChatGPT and grant clipboard permission (login/profile setup)")
+    rootCmd.Flags().StringVarP(&flagTemplate, "template", "t", "", "Send a prompt template (from .chatbang/templates or ~/.config/chatbang/templates) as the first prompt")
+    rootCmd.Flags().StringArrayVar(&flagVars, "var", nil, "Template variable as name=value (repeatable)")
+    rootCmd.Flags().BoolVar(&flagNoContext, "no-context", false, "Do not send the project context file (.chatbang.md or CHATBANG.md)")
+    rootCmd.Flags().StringVar(&flagStyle, "style", "", "Response style: concise, detailed, code-only, none or custom:<text> (overrides config)")
+    rootCmd.PersistentFlags().BoolVar(&flagForceUnlock, "force-unlock", false, "Remove a stale browser profile lock left behind after a crash")
 }
@@ -0,0 +1,235 @@
+package root
+
+import (
+    "fmt"
+    "os"
+    "os/user"
+    "path/filepath"
+    "strings"
+    "text/tabwriter"
+
+    markdown "github.com/MichaelMure/go-term-markdown"
+    "github.com/sirupsen/logrus"
+    "github.com/spf13/cobra"
+
+    "gg/internal/sessions"
+)
+
+var (
+    sessionsRaw    bool
+    sessionsLimit  int
+    sessionsFormat string
+    sessionsOutput string
+    sessionsForce  bool
+)
+
+var sessionsCmd = &cobra.Command{
+    Use:   "sessions",
+    Short: "Browse saved chat transcripts",
+    Long:  "Every REPL session is saved to ~/.config/chatbang/sessions/<id>.jsonl (disable with save_sessions=false). Session ids may be abbreviated to any unique prefix.",
+}
+
+var sessionsListCmd = &cobra.Command{
+    Use:   "list",
+    Short: "List saved sessions, newest first",
+    Args:  cobra.NoArgs,
+    RunE: func(cmd *cobra.Command, args []string) error {
+        store, err := openSessions()
+        if err != nil {
+            return err
+        }
+        list, err := store.List()
+        if err != nil {
+            return err
+        }
+        if len(list) == 0 {
+            fmt.Println("No saved sessions.")
+            return nil
+        }
+        w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+        fmt.Fprintln(w, "ID\tSTARTED\tTURNS\tTITLE")
+        for _, s := range list {
+            fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", s.ID, s.Started.Local().Format("2006-01-02 15:04"), s.Turns, s.Title)
+        }
+        return w.Flush()
+    },
+}
+
+var sessionsShowCmd = &cobra.Command{
+    Use:   "show <id>",
+    Short: "Print a session transcript",
+    Args:  cobra.ExactArgs(1),
+    RunE: func(cmd *cobra.Command, args []string) error {
+        cmd.SilenceUsage = true
+        store, err := openSessions()
+        if err != nil {
+            return err
+        }
+        id, err := store.Resolve(args[0])
+        if err != nil {
+            return err
+        }
+        turns, err := store.Load(id)
+        if err != nil {
+            return err
+        }
+        fmt.Printf("Session %s\n", id)
+        for i, t := range turns {
+            fmt.Printf("\n--- Turn %d, %s", i+1, t.Time.Local().Format("2006-01-02 15:04:05"))
+            if t.Model != "" {
+                fmt.Printf(", %s", t.Model)
+            }
+            fmt.Println(" ---")
+            if t.ConversationURL != "" {
+                fmt.Println(t.ConversationURL)
+            }
+            for _, at := range t.Attachments {
+                fmt.Printf("Attached: %s\n", at.Path)
+            }
+            fmt.Printf("> %s\n\n", strings.ReplaceAll(t.Prompt, "\n", "\n> "))
+            if sessionsRaw {
+                fmt.Println(t.Answer)
+            } else {
+                fmt.Println(string(markdown.Render(t.Answer, 80, 2)))
+            }
+        }
+        return nil
+    },
+}
+
+var sessionsSearchCmd = &cobra.Command{
+    Use:   "search <query>...",
+    Short: "Full-text search across saved sessions (offline)",
+    Long:  "Search prompts and answers of all saved sessions. All words must match; end a word with * to match by prefix.",
+    Args:  cobra.MinimumNArgs(1),
+    RunE: func(cmd *cobra.Command, args []string) error {
+        store, err := openSessions()
+        if err != nil {
+            return err
+        }
+        hits, err := store.Search(strings.Join(args, " "), sessionsLimit)
+        if err != nil {
+            return err
+        }
+        if len(hits) == 0 {
+            fmt.Println("No matches.")
+            return nil
+        }
+        for _, h := range hits {
+            fmt.Printf("%s  turn %d  %s\n    %s\n", h.ID, h.Turn, h.Time.Local().Format("2006-01-02 15:04"), h.Snippet)
+        }
+        return nil
+    },
+}
+
+var sessionsRmCmd = &cobra.Command{
+    Use:   "rm <id>...",
+    Short: "Delete saved sessions",
+    Args:  cobra.MinimumNArgs(1),
+    RunE: func(cmd *cobra.Command, args []string) error {
+        cmd.SilenceUsage = true
+        store, err := openSessions()
+        if err != nil {
+            return err
+        }
+        for _, arg := range args {
+            id, err := store.Remove(arg)
+            if err != nil {
+                return fmt.Errorf("%s: %w", arg, err)
+            }
+            fmt.Printf("Removed %s\n", id)
+        }
+        return nil
+    },
+}
+
+var sessionsExportCmd = &cobra.Command{
+    Use:   "export <id>",
+    Short: "Export a session as Markdown, HTML or JSON",
+    Long:  "Export a session transcript. HTML output is a single self-contained file with syntax-highlighted code blocks; attachments are collapsed. Without --format the format follows the --output extension (default md).",
+    Args:  cobra.ExactArgs(1),
+    RunE: func(cmd *cobra.Command, args []string) error {
+        cmd.SilenceUsage = true
+        store, err := openSessions()
+        if err != nil {
+            return err
+        }
+        id, err := store.Resolve(args[0])
+        if err != nil {
+            return err
+        }
+        turns, err := store.Load(id)
+        if err != nil {
+            return err
+        }
+        format := sessionsFormat
+        if format == "" {
+            format = sessions.FormatForPath(sessionsOutput)
+        }
+        if sessionsOutput == "" || sessionsOutput == "-" {
+            return sessions.Export(os.Stdout, id, turns, format)
+        }
+        if err := sessions.ExportFile(sessionsOutput, id, turns, format); err != nil {
+            return err
+        }
+        fmt.Fprintf(os.Stderr, "Exported %s to %s\n", id, sessionsOutput)
+        return nil
+    },
+}
+
+var sessionsImportCmd = &cobra.Command{
+    Use:   "import <export.zip|conversations.json>",
+    Short: "Import a ChatGPT data export into the local session store",
+    Long:  "Import conversations from ChatGPT's account data export (the .zip or its conversations.json). The active branch of each conversation is stored as a local session so it can be listed, searched and exported offline. Conversations imported before are skipped unless --force is given.",
+    Args:  cobra.ExactArgs(1),
+    RunE: func(cmd *cobra.Command, args []string) error {
+        cmd.SilenceUsage = true
+        store, err := openSessions()
+        if err != nil {
+            return err
+        }
+        convs, err := sessions.ReadChatGPTExport(args[0])
+        if err != nil {
+            return err
+        }
+        imported, skipped := 0, 0
+        for _, c := range convs {
+            id := c.SessionID()
+            if store.Exists(id) && !sessionsForce {
+                skipped++
+                continue
+            }
+            if err := store.Put(id, c.Turns); err != nil {
+                return fmt.Errorf("%s: %w", c.Title, err)
+            }
+            imported++
+        }
+        logrus.WithFields(logrus.Fields{"file": args[0], "imported": imported, "skipped": skipped}).Info("imported ChatGPT export")
+        fmt.Printf("Imported %d conversations", imported)
+        if skipped > 0 {
+            fmt.Printf(" (%d already present; use --force to replace)", skipped)
+        }
+        fmt.Println(".")
+        return store.Reindex()
+    },
+}
+
+// openSessions opens the transcript store in ~/.config/chatbang/sessions.
+func openSessions() (*sessions.Store, error) {
+    usr, err := user.Current()
+    if err != nil {
+        return nil, err
+    }
+    return sessions.Open(filepath.Join(usr.HomeDir, ".config", "chatbang", "sessions"))
+}
+
+func init() {
+    rootCmd.AddCommand(sessionsCmd)
+    sessionsCmd.AddCommand(sessionsListCmd, sessionsShowCmd, sessionsSearchCmd, sessionsRmCmd, sessionsExportCmd, sessionsImportCmd)
+
+    sessionsShowCmd.Flags().BoolVar(&sessionsRaw, "raw", false, "Print answers as Markdown source instead of rendering them")
+    sessionsExportCmd.Flags().StringVarP(&sessionsFormat, "format", "f", "", "Output format: md, html or json")
+    sessionsExportCmd.Flags().StringVarP(&sessionsOutput, "output", "o", "", "Write to this file instead of stdout")
+    sessionsImportCmd.Flags().BoolVar(&sessionsForce, "force", false, "Replace conversations that were imported before")
+    sessionsSearchCmd.Flags().IntVar(&sessionsLimit, "limit", 20, "Maximum number of results (0 for all)")
+}
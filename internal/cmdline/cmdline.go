@@ -0,0 +1,115 @@
+// Package cmdline splits REPL command lines into words, honouring single and
+// double quotes and backslash escapes so paths with spaces can be passed.
+package cmdline
+
+import (
+    "errors"
+    "strings"
+)
+
+// ErrUnterminatedQuote is returned by Split when a quote is left open.
+var ErrUnterminatedQuote = errors.New("unterminated quote")
+
+// Word is one token of a command line.
+type Word struct {
+    Text  string // unquoted text
+    Start int    // byte offset of the word in the line
+    Quote rune   // quote character the word was opened with, if any
+}
+
+// Split tokenizes line. Quotes group words and are removed; a backslash
+// escapes the next character outside single quotes.
+func Split(line string) ([]string, error) {
+    words, open := scan(line)
+    if open {
+        return nil, ErrUnterminatedQuote
+    }
+    out := make([]string, len(words))
+    for i, w := range words {
+        out[i] = w.Text
+    }
+    return out, nil
+}
+
+// SplitPartial tokenizes a line that is still being typed. It returns the
+// complete words and the word under construction at the end of line (empty,
+// with Start == len(line), if line ends in unquoted whitespace).
+func SplitPartial(line string) (words []Word, last Word) {
+    words, open := scan(line)
+    ends := len(line) > 0 && !open && strings.ContainsRune(" \t", rune(line[len(line)-1])) && !escapedAt(line, len(line)-1)
+    if len(words) == 0 || ends {
+        return words, Word{Start: len(line)}
+    }
+    return words[:len(words)-1], words[len(words)-1]
+}
+
+// Quote returns s quoted for a command line if it needs it.
+func Quote(s string) string {
+    if s != "" && !strings.ContainsAny(s, " \t\"'\\") {
+        return s
+    }
+    return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// scan returns the words of line and whether a quote was left open.
+func scan(line string) ([]Word, bool) {
+    var words []Word
+    var cur strings.Builder
+    var quote rune
+    inWord := false
+    start := 0
+    var firstQuote rune
+    flush := func() {
+        if inWord {
+            words = append(words, Word{Text: cur.String(), Start: start, Quote: firstQuote})
+        }
+        cur.Reset()
+        inWord = false
+        firstQuote = 0
+    }
+    runes := []rune(line)
+    offset := 0
+    for i := 0; i < len(runes); i++ {
+        r := runes[i]
+        size := len(string(r))
+        if !inWord && quote == 0 && (r == ' ' || r == '\t') {
+            offset += size
+            continue
+        }
+        if !inWord {
+            inWord = true
+            start = offset
+        }
+        switch {
+        case r == '\\' && quote != '\'' && i+1 < len(runes):
+            i++
+            cur.WriteRune(runes[i])
+            offset += size + len(string(runes[i]))
+            continue
+        case quote != 0 && r == quote:
+            quote = 0
+        case quote == 0 && (r == '"' || r == '\''):
+            quote = r
+            if cur.Len() == 0 && firstQuote == 0 {
+                firstQuote = r
+            }
+        case quote == 0 && (r == ' ' || r == '\t'):
+            flush()
+        default:
+            cur.WriteRune(r)
+        }
+        offset += size
+    }
+    flush()
+    return words, quote != 0
+}
+
+// escapedAt reports whether the byte at i is preceded by an odd number of
+// backslashes.
+func escapedAt(s string, i int) bool {
+    n := 0
+    for j := i - 1; j >= 0 && s[j] == '\\'; j-- {
+        n++
+    }
+    return n%2 == 1
+}
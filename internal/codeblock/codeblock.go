@@ -0,0 +1,165 @@
+// Package codeblock extracts fenced code blocks from Markdown answers.
+package codeblock
+
+import (
+    "path"
+    "strings"
+)
+
+// Block is one fenced code block.
+type Block struct {
+    Lang string // first word of the info string, e.g. "go"
+    Info string // full info string after the fence
+    Code string // content, without the fences, ending in a newline
+    Line int    // 1-based line of the opening fence in the source
+}
+
+// Lines returns the number of lines of code.
+func (b Block) Lines() int {
+    return strings.Count(b.Code, "\n")
+}
+
+// Extract returns the fenced (``` or ~~~) code blocks of md in order. A block
+// left open at the end of md runs to the end.
+func Extract(md string) []Block {
+    var blocks []Block
+    lines := strings.Split(md, "\n")
+    for i := 0; i < len(lines); i++ {
+        indent, char, n, info, ok := openFence(lines[i])
+        if !ok {
+            continue
+        }
+        b := Block{Info: info, Line: i + 1}
+        if f := strings.Fields(info); len(f) > 0 {
+            b.Lang = strings.ToLower(strings.TrimPrefix(f[0], "{."))
+            b.Lang = strings.TrimSuffix(b.Lang, "}")
+            if l, _, ok := strings.Cut(b.Lang, ":"); ok {
+                b.Lang = l
+            } else if ext := path.Ext(b.Lang); ext != "" {
+                b.Lang = strings.TrimPrefix(ext, ".")
+            }
+        }
+        var code strings.Builder
+        for i++; i < len(lines); i++ {
+            if closeFence(lines[i], char, n) {
+                break
+            }
+            code.WriteString(dedent(lines[i], indent))
+            code.WriteByte('\n')
+        }
+        b.Code = code.String()
+        blocks = append(blocks, b)
+    }
+    return blocks
+}
+
+// openFence reports whether line opens a fence, returning its indentation,
+// fence character, fence length and info string.
+func openFence(line string) (indent int, char byte, n int, info string, ok bool) {
+    trimmed := strings.TrimLeft(line, " \t")
+    indent = len(line) - len(trimmed)
+    if len(trimmed) < 3 || (trimmed[0] != '`' && trimmed[0] != '~') {
+        return 0, 0, 0, "", false
+    }
+    char = trimmed[0]
+    for n < len(trimmed) && trimmed[n] == char {
+        n++
+    }
+    if n < 3 {
+        return 0, 0, 0, "", false
+    }
+    info = strings.TrimSpace(trimmed[n:])
+    if char == '`' && strings.ContainsRune(info, '`') {
+        return 0, 0, 0, "", false
+    }
+    return indent, char, n, info, true
+}
+
+func closeFence(line string, char byte, n int) bool {
+    trimmed := strings.TrimSpace(line)
+    if len(trimmed) < n {
+        return false
+    }
+    for i := 0; i < len(trimmed); i++ {
+        if trimmed[i] != char {
+            return false
+        }
+    }
+    return true
+}
+
+// dedent removes up to indent leading spaces, as for blocks nested in lists.
+func dedent(line string, indent int) string {
+    i := 0
+    for i < indent && i < len(line) && (line[i] == ' ' || line[i] == '\t') {
+        i++
+    }
+    return line[i:]
+}
+
+// Filename returns a file name hinted by the info string, as in
+// ```go main.go, ```python title="app.py" or ```cmd/tool/main.go, or "".
+func (b Block) Filename() string {
+    fields := strings.Fields(b.Info)
+    for i, f := range fields {
+        if k, v, ok := strings.Cut(f, "="); ok {
+            switch strings.ToLower(k) {
+            case "title", "file", "filename", "name", "path":
+                return strings.Trim(v, `"'`)
+            }
+            continue
+        }
+        if i == 0 {
+            if _, p, ok := strings.Cut(f, ":"); ok && p != "" {
+                return p
+            }
+            if path.Ext(f) == "" {
+                continue
+            }
+        }
+        if strings.ContainsAny(f, "./") {
+            return strings.Trim(f, `"'`)
+        }
+    }
+    return ""
+}
+
+// Ext returns a file extension for the block's language, e.g. ".go".
+func (b Block) Ext() string {
+    if name := b.Filename(); path.Ext(name) != "" {
+        return path.Ext(name)
+    }
+    switch b.Lang {
+    case "":
+        return ".txt"
+    case "go", "golang":
+        return ".go"
+    case "python", "py", "python3":
+        return ".py"
+    case "javascript", "js", "node":
+        return ".js"
+    case "typescript", "ts":
+        return ".ts"
+    case "bash", "sh", "shell", "zsh", "console":
+        return ".sh"
+    case "rust", "rs":
+        return ".rs"
+    case "ruby", "rb":
+        return ".rb"
+    case "yaml", "yml":
+        return ".yaml"
+    case "markdown", "md":
+        return ".md"
+    case "diff", "patch":
+        return ".diff"
+    case "text", "plaintext", "txt":
+        return ".txt"
+    case "c++", "cpp":
+        return ".cpp"
+    case "csharp", "c#", "cs":
+        return ".cs"
+    case "dockerfile":
+        return ".dockerfile"
+    }
+    return "." + b.Lang
+}
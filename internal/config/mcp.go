@@ -16,6 +16,7 @@ type MCPServerEntry struct {
     MaxBytes      int
     IncludeHidden bool
     AllowBinary   bool
+    AllowWrite    bool
 }
 
 type MCPConfig struct {
@@ -24,7 +25,7 @@ type MCPConfig struct {
 
 // LoadMCPConfig reads a minimal TOML config at path. Supported schema:
 // [[mcp.servers]] tables with keys: name, provider, roots (array of strings),
-// max_bytes (int), include_hidden (bool), allow_binary (bool).
+// max_bytes (int), include_hidden (bool), allow_binary (bool), allow_write (bool).
 func LoadMCPConfig(path string) (MCPConfig, error) {
     var cfg MCPConfig
     f, err := os.Open(path)
@@ -66,6 +67,8 @@ func LoadMCPConfig(path string) (MCPConfig, error) {
                 cur.IncludeHidden = parseBool(v)
             case "allow_binary":
                 cur.AllowBinary = parseBool(v)
+            case "allow_write":
+                cur.AllowWrite = parseBool(v)
             }
         }
     }
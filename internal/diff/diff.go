@@ -0,0 +1,229 @@
+// Package diff renders line-based unified diffs for previews before files are
+// written.
+package diff
+
+import (
+    "fmt"
+    "strings"
+)
+
+// maxCells bounds the LCS table; larger inputs diff as one replaced region.
+const maxCells = 16_000_000
+
+type opKind byte
+
+const (
+    opEqual opKind = ' '
+    opDel   opKind = '-'
+    opAdd   opKind = '+'
+)
+
+type op struct {
+    kind opKind
+    line string
+}
+
+// Unified returns a unified diff turning a into b with context lines of
+// context, or "" if they are equal.
+func Unified(oldName, newName, a, b string, context int) string {
+    if a == b {
+        return ""
+    }
+    ops := diffLines(splitLines(a), splitLines(b))
+    var out strings.Builder
+    fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldName, newName)
+
+    // Walk ops, emitting hunks around changes.
+    i := 0
+    oldLine, newLine := 1, 1
+    for i < len(ops) {
+        if ops[i].kind == opEqual {
+            i++
+            oldLine++
+            newLine++
+            continue
+        }
+        // Start a hunk with up to context lines before the change.
+        start := i
+        for start > 0 && i-start < context && ops[start-1].kind == opEqual {
+            start--
+        }
+        hOld, hNew := oldLine-(i-start), newLine-(i-start)
+        end := i
+        for end < len(ops) {
+            if ops[end].kind != opEqual {
+                end++
+                continue
+            }
+            run := 0
+            for end+run < len(ops) && ops[end+run].kind == opEqual {
+                run++
+            }
+            if end+run == len(ops) || run > 2*context {
+                if run > context {
+                    run = context
+                }
+                end += run
+                break
+            }
+            end += run
+        }
+        var body strings.Builder
+        nOld, nNew := 0, 0
+        for _, o := range ops[start:end] {
+            body.WriteByte(byte(o.kind))
+            body.WriteString(o.line)
+            body.WriteByte('\n')
+            if o.kind != opAdd {
+                nOld++
+            }
+            if o.kind != opDel {
+                nNew++
+            }
+        }
+        fmt.Fprintf(&out, "@@ -%s +%s @@\n", rangeOf(hOld, nOld), rangeOf(hNew, nNew))
+        out.WriteString(body.String())
+        for _, o := range ops[i:end] {
+            if o.kind != opAdd {
+                oldLine++
+            }
+            if o.kind != opDel {
+                newLine++
+            }
+        }
+        i = end
+    }
+    return out.String()
+}
+
+func rangeOf(start, n int) string {
+    if n == 0 {
+        return fmt.Sprintf("%d,0", start-1)
+    }
+    if n == 1 {
+        return fmt.Sprintf("%d", start)
+    }
+    return fmt.Sprintf("%d,%d", start, n)
+}
+
+// Stat counts added and removed lines in a unified diff.
+func Stat(d string) (added, removed int) {
+    for _, l := range strings.Split(d, "\n") {
+        switch {
+        case strings.HasPrefix(l, "+++"), strings.HasPrefix(l, "---"):
+        case strings.HasPrefix(l, "+"):
+            added++
+        case strings.HasPrefix(l, "-"):
+            removed++
+        }
+    }
+    return added, removed
+}
+
+// Colorize adds ANSI colors to a unified diff for terminal display.
+func Colorize(d string) string {
+    lines := strings.SplitAfter(d, "\n")
+    var b strings.Builder
+    for _, l := range lines {
+        color := ""
+        switch {
+        case strings.HasPrefix(l, "+++"), strings.HasPrefix(l, "---"):
+            color = "\x1b[1m"
+        case strings.HasPrefix(l, "@@"):
+            color = "\x1b[36m"
+        case strings.HasPrefix(l, "+"):
+            color = "\x1b[32m"
+        case strings.HasPrefix(l, "-"):
+            color = "\x1b[31m"
+        }
+        if color == "" {
+            b.WriteString(l)
+            continue
+        }
+        body := strings.TrimSuffix(l, "\n")
+        b.WriteString(color + body + "\x1b[0m")
+        if len(body) < len(l) {
+            b.WriteByte('\n')
+        }
+    }
+    return b.String()
+}
+
+func splitLines(s string) []string {
+    if s == "" {
+        return nil
+    }
+    return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffLines computes a line edit script via LCS, after trimming the common
+// prefix and suffix.
+func diffLines(a, b []string) []op {
+    pre := 0
+    for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+        pre++
+    }
+    suf := 0
+    for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+        suf++
+    }
+    var ops []op
+    for _, l := range a[:pre] {
+        ops = append(ops, op{opEqual, l})
+    }
+    ma, mb := a[pre:len(a)-suf], b[pre:len(b)-suf]
+    ops = append(ops, lcsOps(ma, mb)...)
+    for _, l := range a[len(a)-suf:] {
+        ops = append(ops, op{opEqual, l})
+    }
+    return ops
+}
+
+func lcsOps(a, b []string) []op {
+    var ops []op
+    if len(a)*len(b) > maxCells {
+        for _, l := range a {
+            ops = append(ops, op{opDel, l})
+        }
+        for _, l := range b {
+            ops = append(ops, op{opAdd, l})
+        }
+        return ops
+    }
+    // dp[i][j] = LCS length of a[i:] and b[j:].
+    w := len(b) + 1
+    dp := make([]int32, (len(a)+1)*w)
+    for i := len(a) - 1; i >= 0; i-- {
+        for j := len(b) - 1; j >= 0; j-- {
+            if a[i] == b[j] {
+                dp[i*w+j] = dp[(i+1)*w+j+1] + 1
+            } else if dp[(i+1)*w+j] >= dp[i*w+j+1] {
+                dp[i*w+j] = dp[(i+1)*w+j]
+            } else {
+                dp[i*w+j] = dp[i*w+j+1]
+            }
+        }
+    }
+    i, j := 0, 0
+    for i < len(a) && j < len(b) {
+        switch {
+        case a[i] == b[j]:
+            ops = append(ops, op{opEqual, a[i]})
+            i++
+            j++
+        case dp[(i+1)*w+j] >= dp[i*w+j+1]:
+            ops = append(ops, op{opDel, a[i]})
+            i++
+        default:
+            ops = append(ops, op{opAdd, b[j]})
+            j++
+        }
+    }
+    for ; i < len(a); i++ {
+        ops = append(ops, op{opDel, a[i]})
+    }
+    for ; j < len(b); j++ {
+        ops = append(ops, op{opAdd, b[j]})
+    }
+    return ops
+}
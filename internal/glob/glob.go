@@ -0,0 +1,107 @@
+// Package glob matches slash-separated paths against patterns with **
+// ("doublestar") support, for selecting files to attach.
+package glob
+
+import (
+    "path"
+    "strings"
+)
+
+// HasMeta reports whether s contains glob metacharacters.
+func HasMeta(s string) bool {
+    return strings.ContainsAny(s, "*?[")
+}
+
+// Match reports whether name matches pattern. *, ? and [...] match within
+// one path segment, as in path.Match; a ** segment matches any number of
+// segments, including none. Malformed patterns match nothing.
+func Match(pattern, name string) bool {
+    return matchSegments(split(pattern), split(name))
+}
+
+func matchSegments(pat, name []string) bool {
+    for len(pat) > 0 {
+        if pat[0] == "**" {
+            for len(pat) > 1 && pat[1] == "**" {
+                pat = pat[1:]
+            }
+            for i := 0; i <= len(name); i++ {
+                if matchSegments(pat[1:], name[i:]) {
+                    return true
+                }
+            }
+            return false
+        }
+        if len(name) == 0 {
+            return false
+        }
+        if ok, err := path.Match(pat[0], name[0]); err != nil || !ok {
+            return false
+        }
+        pat, name = pat[1:], name[1:]
+    }
+    return len(name) == 0
+}
+
+// Base returns the leading segments of pattern that contain no
+// metacharacters: the directory to search. It is "." if the first segment
+// already has a metacharacter.
+func Base(pattern string) string {
+    segs := split(pattern)
+    n := 0
+    for n < len(segs) && !HasMeta(segs[n]) {
+        n++
+    }
+    if n == 0 {
+        if strings.HasPrefix(pattern, "/") {
+            return "/"
+        }
+        return "."
+    }
+    base := strings.Join(segs[:n], "/")
+    if strings.HasPrefix(pattern, "/") {
+        base = "/" + base
+    }
+    return base
+}
+
+// Depth returns how many directory levels below Base(pattern) a match can
+// be, or -1 if unlimited (the pattern contains **).
+func Depth(pattern string) int {
+    segs := split(pattern)
+    depth := 0
+    for _, s := range segs {
+        if s == "**" {
+            return -1
+        }
+        if depth > 0 || HasMeta(s) {
+            depth++
+        }
+    }
+    return depth
+}
+
+// Excluded reports whether name is matched by an exclusion pattern. Like
+// .gitignore, a pattern without a slash matches any single segment (so
+// "*_test.go" or "testdata" apply at every level); one with a slash matches
+// the path or a directory containing it.
+func Excluded(pattern, name string) bool {
+    pattern = strings.TrimSuffix(pattern, "/")
+    if !strings.Contains(pattern, "/") {
+        for _, s := range split(name) {
+            if ok, _ := path.Match(pattern, s); ok {
+                return true
+            }
+        }
+        return false
+    }
+    return Match(pattern, name) || Match(pattern+"/**", name)
+}
+
+func split(p string) []string {
+    p = path.Clean(p)
+    if p == "." || p == "/" {
+        return nil
+    }
+    return strings.Split(strings.TrimPrefix(p, "/"), "/")
+}
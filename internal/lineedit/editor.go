@@ -0,0 +1,675 @@
+// Package lineedit is a small readline-style line editor for the chat REPL:
+// cursor movement, persistent history, reverse search and bracketed paste on
+// a raw terminal, with a plain line reader fallback when stdin is not a
+// terminal. Pasted text may span lines; it stays in the buffer until Enter.
+package lineedit
+
+import (
+    "bufio"
+    "errors"
+    "fmt"
+    "io"
+    "os"
+    "strings"
+    "unicode"
+
+    "github.com/mattn/go-runewidth"
+    "golang.org/x/term"
+)
+
+// ErrInterrupted is returned by ReadLine when the user presses Ctrl-C.
+var ErrInterrupted = errors.New("interrupted")
+
+// Editor reads lines from a terminal with editing support.
+type Editor struct {
+    in  *os.File
+    r   *bufio.Reader
+    out io.Writer
+
+    // History, if set, is browsed with Up/Down and searched with Ctrl-R.
+    History *History
+    // Complete, if set, is called on Tab with the line and cursor position.
+    // It returns candidates replacing line[start:pos] (in runes).
+    Complete func(line []rune, pos int) (candidates []string, start int)
+}
+
+// New creates an editor reading from in and drawing to out.
+func New(in *os.File, out io.Writer, h *History) *Editor {
+    return &Editor{in: in, r: bufio.NewReader(in), out: out, History: h}
+}
+
+// IsTerminal reports whether input comes from an interactive terminal.
+func (e *Editor) IsTerminal() bool {
+    return term.IsTerminal(int(e.in.Fd()))
+}
+
+// AddHistory records line in the history, if any.
+func (e *Editor) AddHistory(line string) error {
+    if e.History == nil {
+        return nil
+    }
+    return e.History.Add(line)
+}
+
+// ReadLine shows prompt and returns the entered line without its newline.
+// It returns io.EOF on Ctrl-D at an empty line (or end of input) and
+// ErrInterrupted on Ctrl-C.
+func (e *Editor) ReadLine(prompt string) (string, error) {
+    if !e.IsTerminal() {
+        return e.readPlain(prompt)
+    }
+    fd := int(e.in.Fd())
+    state, err := term.MakeRaw(fd)
+    if err != nil {
+        return e.readPlain(prompt)
+    }
+    defer term.Restore(fd, state)
+    // Ask the terminal to wrap pastes in ESC[200~ ... ESC[201~.
+    io.WriteString(e.out, "\x1b[?2004h")
+    defer io.WriteString(e.out, "\x1b[?2004l")
+
+    ls := &lineState{e: e, prompt: prompt}
+    if e.History != nil {
+        ls.histIdx = len(e.History.Entries())
+    }
+    ls.refresh()
+    return ls.edit()
+}
+
+func (e *Editor) readPlain(prompt string) (string, error) {
+    fmt.Fprint(e.out, prompt)
+    line, err := e.r.ReadString('\n')
+    if err != nil && (err != io.EOF || line == "") {
+        return "", err
+    }
+    return strings.TrimRight(line, "\r\n"), nil
+}
+
+type keyCode int
+
+const (
+    keyRune keyCode = iota
+    keyEnter
+    keyTab
+    keyBackspace
+    keyDelete
+    keyLeft
+    keyRight
+    keyUp
+    keyDown
+    keyHome
+    keyEnd
+    keyWordLeft
+    keyWordRight
+    keyDeleteWordBack
+    keyKillEnd
+    keyKillStart
+    keyClear
+    keySearch
+    keyInterrupt
+    keyEOF
+    keyEsc
+    keyCancel
+    keyPaste
+    keyUnknown
+)
+
+type key struct {
+    code  keyCode
+    r     rune
+    paste string // text for keyPaste
+}
+
+// readKey decodes one keypress, including common ANSI escape sequences.
+func (e *Editor) readKey() (key, error) {
+    b, err := e.r.ReadByte()
+    if err != nil {
+        return key{}, err
+    }
+    switch b {
+    case 1:
+        return key{code: keyHome}, nil
+    case 2:
+        return key{code: keyLeft}, nil
+    case 3:
+        return key{code: keyInterrupt}, nil
+    case 4:
+        return key{code: keyEOF}, nil
+    case 5:
+        return key{code: keyEnd}, nil
+    case 6:
+        return key{code: keyRight}, nil
+    case 7:
+        return key{code: keyCancel}, nil
+    case 8, 127:
+        return key{code: keyBackspace}, nil
+    case 9:
+        return key{code: keyTab}, nil
+    case 10, 13:
+        return key{code: keyEnter}, nil
+    case 11:
+        return key{code: keyKillEnd}, nil
+    case 12:
+        return key{code: keyClear}, nil
+    case 14:
+        return key{code: keyDown}, nil
+    case 16:
+        return key{code: keyUp}, nil
+    case 18:
+        return key{code: keySearch}, nil
+    case 21:
+        return key{code: keyKillStart}, nil
+    case 23:
+        return key{code: keyDeleteWordBack}, nil
+    case 27:
+        return e.readEscape()
+    }
+    if b < 32 {
+        return key{code: keyUnknown}, nil
+    }
+    if err := e.r.UnreadByte(); err != nil {
+        return key{}, err
+    }
+    r, _, err := e.r.ReadRune()
+    if err != nil {
+        return key{}, err
+    }
+    return key{code: keyRune, r: r}, nil
+}
+
+// readEscape decodes the bytes following ESC. A lone ESC (nothing buffered)
+// is reported as keyEsc.
+func (e *Editor) readEscape() (key, error) {
+    if e.r.Buffered() == 0 {
+        return key{code: keyEsc}, nil
+    }
+    b, err := e.r.ReadByte()
+    if err != nil {
+        return key{}, err
+    }
+    switch b {
+    case 'b', 'B':
+        return key{code: keyWordLeft}, nil
+    case 'f', 'F':
+        return key{code: keyWordRight}, nil
+    case 127, 8:
+        return key{code: keyDeleteWordBack}, nil
+    case '[', 'O':
+    default:
+        return key{code: keyUnknown}, nil
+    }
+    // CSI/SS3: parameter bytes then a final byte in 0x40..0x7e.
+    var params strings.Builder
+    for {
+        c, err := e.r.ReadByte()
+        if err != nil {
+            return key{}, err
+        }
+        if c >= 0x40 && c <= 0x7e {
+            if c == '~' && params.String() == "200" {
+                return e.readPaste()
+            }
+            return csiKey(params.String(), c), nil
+        }
+        params.WriteByte(c)
+    }
+}
+
+// pasteEnd terminates a bracketed paste.
+const pasteEnd = "\x1b[201~"
+
+// readPaste collects bracketed-paste text up to ESC[201~. Line endings are
+// normalized to \n and a single trailing newline is dropped so the paste
+// isn't sent before the user presses Enter.
+func (e *Editor) readPaste() (key, error) {
+    var b strings.Builder
+    for {
+        c, err := e.r.ReadByte()
+        if err != nil {
+            return key{}, err
+        }
+        b.WriteByte(c)
+        if c == '~' && strings.HasSuffix(b.String(), pasteEnd) {
+            break
+        }
+    }
+    text := strings.TrimSuffix(b.String(), pasteEnd)
+    text = strings.ReplaceAll(text, "\r\n", "\n")
+    text = strings.ReplaceAll(text, "\r", "\n")
+    text = strings.TrimSuffix(text, "\n")
+    return key{code: keyPaste, paste: text}, nil
+}
+
+func csiKey(params string, final byte) key {
+    switch final {
+    case 'A':
+        return key{code: keyUp}
+    case 'B':
+        return key{code: keyDown}
+    case 'C':
+        if strings.Contains(params, ";") {
+            return key{code: keyWordRight}
+        }
+        return key{code: keyRight}
+    case 'D':
+        if strings.Contains(params, ";") {
+            return key{code: keyWordLeft}
+        }
+        return key{code: keyLeft}
+    case 'H':
+        return key{code: keyHome}
+    case 'F':
+        return key{code: keyEnd}
+    case '~':
+        switch params {
+        case "1", "7":
+            return key{code: keyHome}
+        case "4", "8":
+            return key{code: keyEnd}
+        case "3":
+            return key{code: keyDelete}
+        }
+    }
+    return key{code: keyUnknown}
+}
+
+// lineState is the buffer and screen bookkeeping for one ReadLine call.
+type lineState struct {
+    e      *Editor
+    prompt string
+    buf    []rune
+    pos    int
+    oldRow int // cursor row (relative to the prompt's first row) after last refresh
+
+    histIdx int    // position while browsing history; len(entries) is the live line
+    saved   []rune // live line stashed while browsing history
+}
+
+func (ls *lineState) edit() (string, error) {
+    for {
+        k, err := ls.e.readKey()
+        if err != nil {
+            if err == io.EOF && len(ls.buf) > 0 {
+                ls.finish()
+                return string(ls.buf), nil
+            }
+            ls.write("\r\n")
+            return "", err
+        }
+        switch k.code {
+        case keyRune:
+            ls.insert([]rune{k.r})
+        case keyPaste:
+            ls.insert([]rune(k.paste))
+        case keyTab:
+            ls.complete()
+        case keyEnter:
+            ls.finish()
+            return string(ls.buf), nil
+        case keyInterrupt:
+            ls.pos = len(ls.buf)
+            ls.refresh()
+            ls.write("^C\r\n")
+            return "", ErrInterrupted
+        case keyEOF:
+            if len(ls.buf) == 0 {
+                ls.write("\r\n")
+                return "", io.EOF
+            }
+            ls.deleteAt(ls.pos)
+        case keyBackspace:
+            if ls.pos > 0 {
+                ls.pos--
+                ls.deleteAt(ls.pos)
+            }
+        case keyDelete:
+            ls.deleteAt(ls.pos)
+        case keyLeft:
+            if ls.pos > 0 {
+                ls.pos--
+                ls.refresh()
+            }
+        case keyRight:
+            if ls.pos < len(ls.buf) {
+                ls.pos++
+                ls.refresh()
+            }
+        case keyHome:
+            ls.pos = 0
+            ls.refresh()
+        case keyEnd:
+            ls.pos = len(ls.buf)
+            ls.refresh()
+        case keyWordLeft:
+            ls.pos = ls.wordStart()
+            ls.refresh()
+        case keyWordRight:
+            ls.pos = ls.wordEnd()
+            ls.refresh()
+        case keyDeleteWordBack:
+            start := ls.wordStart()
+            ls.buf = append(ls.buf[:start], ls.buf[ls.pos:]...)
+            ls.pos = start
+            ls.refresh()
+        case keyKillEnd:
+            ls.buf = ls.buf[:ls.pos]
+            ls.refresh()
+        case keyKillStart:
+            ls.buf = append([]rune{}, ls.buf[ls.pos:]...)
+            ls.pos = 0
+            ls.refresh()
+        case keyClear:
+            ls.write("\x1b[H\x1b[2J")
+            ls.oldRow = 0
+            ls.refresh()
+        case keyUp:
+            ls.historyMove(-1)
+        case keyDown:
+            ls.historyMove(1)
+        case keySearch:
+            if done, err := ls.search(); done {
+                return string(ls.buf), err
+            }
+        }
+    }
+}
+
+// finish moves the cursor past the line and ends it.
+func (ls *lineState) finish() {
+    ls.pos = len(ls.buf)
+    ls.refresh()
+    ls.write("\r\n")
+}
+
+func (ls *lineState) insert(rs []rune) {
+    buf := make([]rune, 0, len(ls.buf)+len(rs))
+    buf = append(buf, ls.buf[:ls.pos]...)
+    buf = append(buf, rs...)
+    buf = append(buf, ls.buf[ls.pos:]...)
+    ls.buf = buf
+    ls.pos += len(rs)
+    ls.refresh()
+}
+
+func (ls *lineState) deleteAt(i int) {
+    if i < 0 || i >= len(ls.buf) {
+        return
+    }
+    ls.buf = append(ls.buf[:i], ls.buf[i+1:]...)
+    ls.refresh()
+}
+
+func (ls *lineState) wordStart() int {
+    i := ls.pos
+    for i > 0 && unicode.IsSpace(ls.buf[i-1]) {
+        i--
+    }
+    for i > 0 && !unicode.IsSpace(ls.buf[i-1]) {
+        i--
+    }
+    return i
+}
+
+func (ls *lineState) wordEnd() int {
+    i := ls.pos
+    for i < len(ls.buf) && unicode.IsSpace(ls.buf[i]) {
+        i++
+    }
+    for i < len(ls.buf) && !unicode.IsSpace(ls.buf[i]) {
+        i++
+    }
+    return i
+}
+
+func (ls *lineState) historyMove(delta int) {
+    if ls.e.History == nil {
+        return
+    }
+    entries := ls.e.History.Entries()
+    next := ls.histIdx + delta
+    if next < 0 || next > len(entries) {
+        return
+    }
+    if ls.histIdx == len(entries) {
+        ls.saved = append([]rune{}, ls.buf...)
+    }
+    ls.histIdx = next
+    if next == len(entries) {
+        ls.buf = append([]rune{}, ls.saved...)
+    } else {
+        ls.buf = []rune(entries[next])
+    }
+    ls.pos = len(ls.buf)
+    ls.refresh()
+}
+
+// complete handles Tab: a single candidate replaces the word, several extend
+// it to their common prefix, and if that adds nothing they are listed.
+func (ls *lineState) complete() {
+    if ls.e.Complete == nil {
+        return
+    }
+    cands, start := ls.e.Complete(ls.buf, ls.pos)
+    if len(cands) == 0 || start < 0 || start > ls.pos {
+        return
+    }
+    word := string(ls.buf[start:ls.pos])
+    repl := cands[0]
+    if len(cands) > 1 {
+        repl = commonPrefix(cands)
+    }
+    if (repl != word && strings.HasPrefix(repl, word)) || len(cands) == 1 {
+        rest := append([]rune{}, ls.buf[ls.pos:]...)
+        ls.buf = append(append(ls.buf[:start], []rune(repl)...), rest...)
+        ls.pos = start + len([]rune(repl))
+        // A unique, finished word gets a separator (directories stay open).
+        if len(cands) == 1 && !strings.HasSuffix(repl, "/") && !strings.HasSuffix(repl, "=") {
+            if ls.pos == len(ls.buf) || ls.buf[ls.pos] != ' ' {
+                ls.buf = append(ls.buf[:ls.pos], append([]rune{' '}, ls.buf[ls.pos:]...)...)
+            }
+            ls.pos++
+        }
+        ls.refresh()
+        return
+    }
+    ls.listCandidates(cands)
+}
+
+// listCandidates prints candidates in columns below the line and redraws it.
+func (ls *lineState) listCandidates(cands []string) {
+    pos := ls.pos
+    ls.pos = len(ls.buf)
+    ls.refresh()
+    ls.pos = pos
+    width := 0
+    for _, c := range cands {
+        if w := runewidth.StringWidth(c); w > width {
+            width = w
+        }
+    }
+    width += 2
+    perRow := ls.e.columns() / width
+    if perRow < 1 {
+        perRow = 1
+    }
+    var b strings.Builder
+    b.WriteString("\r\n")
+    for i, c := range cands {
+        b.WriteString(runewidth.FillRight(c, width))
+        if (i+1)%perRow == 0 || i == len(cands)-1 {
+            b.WriteString("\r\n")
+        }
+    }
+    ls.write(b.String())
+    ls.oldRow = 0
+    ls.refresh()
+}
+
+func commonPrefix(ss []string) string {
+    p := []rune(ss[0])
+    for _, s := range ss[1:] {
+        r := []rune(s)
+        n := 0
+        for n < len(p) && n < len(r) && p[n] == r[n] {
+            n++
+        }
+        p = p[:n]
+    }
+    return string(p)
+}
+
+// search runs an incremental reverse history search (Ctrl-R). It returns
+// done=true when Enter accepted a match as the final line; otherwise the
+// match (if any) is left in the buffer for further editing.
+func (ls *lineState) search() (done bool, err error) {
+    if ls.e.History == nil {
+        return false, nil
+    }
+    entries := ls.e.History.Entries()
+    origBuf, origPos, origPrompt := ls.buf, ls.pos, ls.prompt
+    var query []rune
+    idx := len(entries) // search starts below this index
+    match := -1
+    find := func(from int) {
+        q := string(query)
+        for i := from - 1; i >= 0; i-- {
+            if q == "" || strings.Contains(entries[i], q) {
+                match = i
+                return
+            }
+        }
+    }
+    show := func() {
+        label := "reverse-i-search"
+        if match < 0 && len(query) > 0 {
+            label = "failing reverse-i-search"
+        }
+        ls.prompt = fmt.Sprintf("(%s)`%s': ", label, string(query))
+        if match >= 0 {
+            ls.buf = []rune(entries[match])
+            ls.pos = 0
+            if i := strings.Index(entries[match], string(query)); i >= 0 {
+                ls.pos = len([]rune(entries[match][:i]))
+            }
+        } else {
+            ls.buf, ls.pos = origBuf, origPos
+        }
+        ls.refresh()
+    }
+    show()
+    for {
+        k, err := ls.e.readKey()
+        if err != nil {
+            return true, err
+        }
+        switch k.code {
+        case keyRune:
+            query = append(query, k.r)
+            if match >= 0 {
+                idx = match + 1
+            }
+            match = -1
+            find(idx)
+        case keyBackspace:
+            if len(query) > 0 {
+                query = query[:len(query)-1]
+                match = -1
+                find(len(entries))
+            }
+        case keySearch:
+            if match >= 0 {
+                idx = match
+                prev := match
+                match = -1
+                find(idx)
+                if match < 0 {
+                    match = prev
+                }
+            }
+        case keyCancel, keyEsc, keyInterrupt:
+            ls.prompt = origPrompt
+            ls.buf, ls.pos = origBuf, origPos
+            ls.refresh()
+            return false, nil
+        case keyEnter:
+            ls.prompt = origPrompt
+            ls.finish()
+            return true, nil
+        default:
+            // Any movement key accepts the match and resumes editing.
+            ls.prompt = origPrompt
+            ls.histIdx = len(entries)
+            ls.refresh()
+            return false, nil
+        }
+        show()
+    }
+}
+
+func (ls *lineState) write(s string) {
+    io.WriteString(ls.e.out, s)
+}
+
+// refresh redraws prompt and buffer, handling lines that wrap across rows
+// and embedded newlines from pastes.
+func (ls *lineState) refresh() {
+    cols := ls.e.columns()
+    row, col := 0, 0
+    advance := func(r rune) {
+        if r == '\n' {
+            row, col = row+1, 0
+            return
+        }
+        w := runewidth.RuneWidth(r)
+        if col+w > cols {
+            row, col = row+1, 0
+        }
+        col += w
+    }
+    for _, r := range ls.prompt {
+        advance(r)
+    }
+    curRow, curCol := -1, 0
+    for i, r := range ls.buf {
+        if i == ls.pos {
+            curRow, curCol = row, col
+        }
+        advance(r)
+    }
+    endRow, endCol := row, col
+
+    var b strings.Builder
+    if ls.oldRow > 0 {
+        fmt.Fprintf(&b, "\x1b[%dA", ls.oldRow)
+    }
+    b.WriteString("\r\x1b[J")
+    b.WriteString(ls.prompt)
+    b.WriteString(strings.ReplaceAll(string(ls.buf), "\n", "\r\n"))
+    // At exactly the full width the terminal defers wrapping; force it so the
+    // cursor math below holds.
+    if endCol >= cols {
+        b.WriteString("\r\n")
+        endRow, endCol = endRow+1, 0
+    }
+    if curRow < 0 {
+        curRow, curCol = endRow, endCol
+    } else if curCol >= cols {
+        curRow, curCol = curRow+1, 0
+    }
+    if up := endRow - curRow; up > 0 {
+        fmt.Fprintf(&b, "\x1b[%dA", up)
+    }
+    b.WriteString("\r")
+    if curCol > 0 {
+        fmt.Fprintf(&b, "\x1b[%dC", curCol)
+    }
+    ls.oldRow = curRow
+    ls.write(b.String())
+}
+
+func (e *Editor) columns() int {
+    if w, _, err := term.GetSize(int(e.in.Fd())); err == nil && w > 0 {
+        return w
+    }
+    return 80
+}
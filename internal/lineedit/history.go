@@ -0,0 +1,110 @@
+package lineedit
+
+import (
+    "bufio"
+    "errors"
+    "os"
+    "path/filepath"
+    "strings"
+)
+
+// DefaultHistorySize caps the number of remembered lines.
+const DefaultHistorySize = 1000
+
+// History is a deduplicated, size-capped list of input lines persisted to a
+// file (one entry per line; embedded newlines are escaped).
+type History struct {
+    path    string
+    max     int
+    entries []string
+}
+
+// LoadHistory reads history from path. A missing file yields an empty history;
+// an empty path keeps history in memory only.
+func LoadHistory(path string, max int) (*History, error) {
+    if max <= 0 {
+        max = DefaultHistorySize
+    }
+    h := &History{path: path, max: max}
+    if path == "" {
+        return h, nil
+    }
+    f, err := os.Open(path)
+    if err != nil {
+        if errors.Is(err, os.ErrNotExist) {
+            return h, nil
+        }
+        return h, err
+    }
+    defer f.Close()
+    sc := bufio.NewScanner(f)
+    sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+    for sc.Scan() {
+        if line := unescape(sc.Text()); line != "" {
+            h.push(line)
+        }
+    }
+    return h, sc.Err()
+}
+
+// Entries returns the history, oldest first.
+func (h *History) Entries() []string { return h.entries }
+
+// Add records line (dropping any earlier copy) and persists the history.
+func (h *History) Add(line string) error {
+    if strings.TrimSpace(line) == "" {
+        return nil
+    }
+    h.push(line)
+    return h.save()
+}
+
+func (h *History) push(line string) {
+    for i, e := range h.entries {
+        if e == line {
+            h.entries = append(h.entries[:i], h.entries[i+1:]...)
+            break
+        }
+    }
+    h.entries = append(h.entries, line)
+    if len(h.entries) > h.max {
+        h.entries = h.entries[len(h.entries)-h.max:]
+    }
+}
+
+// save rewrites the history file atomically; it may hold prompts, so it is
+// only readable by the user.
+func (h *History) save() error {
+    if h.path == "" {
+        return nil
+    }
+    if err := os.MkdirAll(filepath.Dir(h.path), 0o755); err != nil {
+        return err
+    }
+    tmp := h.path + ".tmp"
+    f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+    if err != nil {
+        return err
+    }
+    w := bufio.NewWriter(f)
+    for _, e := range h.entries {
+        w.WriteString(escape(e))
+        w.WriteByte('\n')
+    }
+    if err := w.Flush(); err != nil {
+        f.Close()
+        return err
+    }
+    if err := f.Close(); err != nil {
+        return err
+    }
+    return os.Rename(tmp, h.path)
+}
+
+var (
+    escaper   = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+    unescaper = strings.NewReplacer(`\\`, `\`, `\n`, "\n")
+)
+
+func escape(s string) string   { return escaper.Replace(s) }
+func unescape(s string) string { return unescaper.Replace(s) }
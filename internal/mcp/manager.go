@@ -35,6 +35,7 @@ func (m *Manager) LoadFromConfig(path string) error {
             "maxBytes":      fallbackInt(s.MaxBytes, 1_048_576),
             "includeHidden": s.IncludeHidden,
             "allowBinary":   s.AllowBinary,
+            "allowWrite":    s.AllowWrite,
         }
         p, err := f(opts)
         if err != nil { return err }
@@ -0,0 +1,271 @@
+// Package outline finds the lines of a named symbol (function, method, type
+// or class) in a source file: with go/parser for Go and a regex outline for
+// other languages.
+package outline
+
+import (
+    "fmt"
+    "go/ast"
+    "go/parser"
+    "go/token"
+    "path/filepath"
+    "regexp"
+    "strings"
+)
+
+// Range is a 1-based, inclusive line range.
+type Range struct {
+    Start, End int
+}
+
+// Find returns the lines of symbol in src. symbol may be qualified by its
+// type or class, e.g. "App.Run" or "Parser.parse".
+func Find(path, src, symbol string) (Range, error) {
+    if symbol == "" {
+        return Range{}, fmt.Errorf("empty symbol name")
+    }
+    if strings.EqualFold(filepath.Ext(path), ".go") {
+        fset := token.NewFileSet()
+        // Files that do not parse fall back to the outline.
+        if f, err := parser.ParseFile(fset, path, src, parser.ParseComments); err == nil {
+            return findGo(fset, f, path, symbol)
+        }
+    }
+    lines := strings.Split(strings.TrimSuffix(src, "\n"), "\n")
+    r := Range{Start: 1, End: len(lines)}
+    for _, part := range strings.Split(symbol, ".") {
+        found, ok := findOutline(lines, r, part)
+        if !ok {
+            return Range{}, fmt.Errorf("%s not found in %s", symbol, path)
+        }
+        r = found
+    }
+    return r, nil
+}
+
+// findGo looks symbol up among the top-level declarations. Methods match
+// "Recv.Name" or, if no plain function has that name and only one type has
+// such a method, plain "Name". Doc comments are included.
+func findGo(fset *token.FileSet, f *ast.File, path, symbol string) (Range, error) {
+    recv, name, qualified := strings.Cut(symbol, ".")
+    if !qualified {
+        name, recv = symbol, ""
+    }
+    span := func(from, to token.Pos) Range {
+        return Range{Start: fset.Position(from).Line, End: fset.Position(to).Line}
+    }
+    var matches []Range
+    var candidates []string
+    for _, d := range f.Decls {
+        switch d := d.(type) {
+        case *ast.FuncDecl:
+            if d.Name.Name != name {
+                continue
+            }
+            got := receiverName(d)
+            if qualified && got != recv {
+                continue
+            }
+            from := d.Pos()
+            if d.Doc != nil {
+                from = d.Doc.Pos()
+            }
+            r := span(from, d.End())
+            if got == "" {
+                // A plain function wins over methods of the same name.
+                return r, nil
+            }
+            matches = append(matches, r)
+            candidates = append(candidates, got+"."+name)
+        case *ast.GenDecl:
+            if qualified {
+                continue
+            }
+            for _, spec := range d.Specs {
+                var names []*ast.Ident
+                switch s := spec.(type) {
+                case *ast.TypeSpec:
+                    names = []*ast.Ident{s.Name}
+                case *ast.ValueSpec:
+                    names = s.Names
+                }
+                for _, id := range names {
+                    if id.Name != name {
+                        continue
+                    }
+                    // A single spec covers its whole declaration; one in a
+                    // group covers just itself.
+                    from, to := spec.Pos(), spec.End()
+                    if len(d.Specs) == 1 {
+                        from, to = d.Pos(), d.End()
+                        if d.Doc != nil {
+                            from = d.Doc.Pos()
+                        }
+                    }
+                    return span(from, to), nil
+                }
+            }
+        }
+    }
+    switch len(matches) {
+    case 0:
+        return Range{}, fmt.Errorf("%s not found in %s", symbol, path)
+    case 1:
+        return matches[0], nil
+    }
+    return Range{}, fmt.Errorf("%s is ambiguous in %s: qualify it as one of %s", symbol, path, strings.Join(candidates, ", "))
+}
+
+func receiverName(d *ast.FuncDecl) string {
+    if d.Recv == nil || len(d.Recv.List) == 0 {
+        return ""
+    }
+    t := d.Recv.List[0].Type
+    for {
+        switch x := t.(type) {
+        case *ast.StarExpr:
+            t = x.X
+        case *ast.IndexExpr:
+            t = x.X
+        case *ast.IndexListExpr:
+            t = x.X
+        case *ast.Ident:
+            return x.Name
+        default:
+            return ""
+        }
+    }
+}
+
+// definition patterns for the regex outline, most specific first. NAME is
+// replaced by the quoted symbol name.
+var definitions = []string{
+    // def, class, fn, func, function, struct, ... with optional modifiers.
+    `^\s*(?:(?:export|default|pub(?:\([^)]*\))?|async|static|public|private|protected|abstract|final|override|unsafe|extern|inline|virtual)\s+)*(?:def|class|function\*?|fn|func|struct|enum|trait|interface|impl|type|module|object|record|sub|proc|macro_rules!)\s+NAME\b`,
+    // const name = ..., let name = (...) => ...
+    `^\s*(?:export\s+)?(?:const|let|var|val)\s+NAME\s*[:=]`,
+    // Object or class members: name(...) {, name: function, async name(
+    `^\s*(?:(?:public|private|protected|static|async|get|set|override|virtual|final|abstract|synchronized)\s+)*NAME\s*(?:\(|:\s*(?:async\s+)?function)`,
+    // C-like functions and methods: ReturnType name(args) not ending in ;
+    `^\s*[\w:<>\[\],*&\s]+?\bNAME\s*\([^;]*$`,
+}
+
+// findOutline finds the definition of name within r and its extent. The
+// least indented match wins, so a top-level function beats a method of the
+// same name.
+func findOutline(lines []string, r Range, name string) (Range, bool) {
+    quoted := regexp.QuoteMeta(name)
+    for _, def := range definitions {
+        re := regexp.MustCompile(strings.ReplaceAll(def, "NAME", quoted))
+        best := -1
+        for i := r.Start - 1; i < r.End && i < len(lines); i++ {
+            if re.MatchString(lines[i]) && (best < 0 || indentOf(lines[i]) < indentOf(lines[best])) {
+                best = i
+            }
+        }
+        if best >= 0 {
+            return Range{Start: best + 1, End: blockEnd(lines, best, r.End) + 1}, true
+        }
+    }
+    return Range{}, false
+}
+
+// blockEnd returns the index of the last line of the block starting at
+// line start: up to the matching brace if one opens within the first few
+// lines, else the indented block (Python, Ruby-like), else the line itself.
+func blockEnd(lines []string, start, limit int) int {
+    depth, opened := 0, false
+    for i := start; i < limit && i < len(lines); i++ {
+        if !opened && i > start+3 {
+            break
+        }
+        for _, c := range stripStrings(lines[i]) {
+            switch c {
+            case '{':
+                depth++
+                opened = true
+            case '}':
+                depth--
+            }
+        }
+        if opened && depth <= 0 {
+            return i
+        }
+        if !opened && strings.HasSuffix(strings.TrimSpace(stripComment(lines[i])), ";") {
+            return i
+        }
+    }
+    if opened {
+        return min(limit, len(lines)) - 1
+    }
+    indent := indentOf(lines[start])
+    end := start
+    for i := start + 1; i < limit && i < len(lines); i++ {
+        if strings.TrimSpace(lines[i]) == "" {
+            continue
+        }
+        if indentOf(lines[i]) <= indent {
+            if t := strings.TrimSpace(lines[i]); t == "end" || strings.HasPrefix(t, "end ") {
+                end = i
+            }
+            break
+        }
+        end = i
+    }
+    return end
+}
+
+func indentOf(line string) int {
+    n := 0
+    for _, c := range line {
+        switch c {
+        case ' ':
+            n++
+        case '\t':
+            n += 4
+        default:
+            return n
+        }
+    }
+    return n
+}
+
+var charLiteral = regexp.MustCompile(`'(?:\\.|[^'\\])'`)
+
+// stripStrings blanks out quoted strings and line comments so braces in
+// them are not counted.
+func stripStrings(line string) string {
+    // Character literals such as '{' are blanked first; other single
+    // quotes (Rust lifetimes, apostrophes) do not start strings.
+    line = charLiteral.ReplaceAllString(stripComment(line), "")
+    var b strings.Builder
+    var quote rune
+    escaped := false
+    for _, c := range line {
+        switch {
+        case quote != 0:
+            if escaped {
+                escaped = false
+            } else if c == '\\' {
+                escaped = true
+            } else if c == quote {
+                quote = 0
+            }
+        case c == '"' || c == '`':
+            quote = c
+        default:
+            b.WriteRune(c)
+        }
+    }
+    return b.String()
+}
+
+func stripComment(line string) string {
+    if i := strings.Index(line, "//"); i >= 0 {
+        return line[:i]
+    }
+    if t := strings.TrimSpace(line); strings.HasPrefix(t, "#") && !strings.HasPrefix(t, "#[") {
+        return ""
+    }
+    return line
+}
@@ -0,0 +1,369 @@
+// Package patch parses unified diffs, as found in chat answers, and applies
+// their hunks with offset and fuzz tolerance.
+package patch
+
+import (
+    "errors"
+    "fmt"
+    "regexp"
+    "strconv"
+    "strings"
+)
+
+// DevNull names the missing side of a created or deleted file.
+const DevNull = "/dev/null"
+
+// File is the diff of one file.
+type File struct {
+    Old, New string // paths with a/ and b/ prefixes removed
+    Hunks    []Hunk
+}
+
+// Path returns the file the diff applies to.
+func (f File) Path() string {
+    if f.New != "" && f.New != DevNull {
+        return f.New
+    }
+    return f.Old
+}
+
+// Hunk is one @@ section. Lines keep their ' ', '-' or '+' prefix.
+type Hunk struct {
+    OldStart int // 1-based; 0 if the header had no line numbers
+    NewStart int
+    Section  string // text after the closing @@, usually a function name
+    Lines    []string
+}
+
+var hunkHeader = regexp.MustCompile(`^@@+ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@+ ?(.*)$`)
+
+// ErrNoHunks is returned by Parse when the text contains no hunks.
+var ErrNoHunks = errors.New("no diff hunks found")
+
+// LooksLikePatch reports whether text appears to be a unified diff.
+func LooksLikePatch(text string) bool {
+    hasHunk, hasHeader := false, false
+    for _, l := range strings.Split(text, "\n") {
+        switch {
+        case strings.HasPrefix(l, "@@"):
+            hasHunk = true
+        case strings.HasPrefix(l, "--- "), strings.HasPrefix(l, "+++ "), strings.HasPrefix(l, "diff --git "):
+            hasHeader = true
+        }
+    }
+    return hasHunk && hasHeader
+}
+
+// Parse reads a unified diff. Hunk line counts in headers are not trusted,
+// since hand-written diffs often get them wrong; a hunk runs until the next
+// hunk or file header.
+func Parse(text string) ([]File, error) {
+    lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+    var files []File
+    var cur *File
+    var hunk *Hunk
+    newFile := func(old, new string) {
+        files = append(files, File{Old: old, New: new})
+        cur = &files[len(files)-1]
+        hunk = nil
+    }
+    for i := 0; i < len(lines); i++ {
+        l := lines[i]
+        switch {
+        case strings.HasPrefix(l, "diff --git "):
+            old, new := gitNames(strings.TrimPrefix(l, "diff --git "))
+            newFile(old, new)
+        case strings.HasPrefix(l, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+            old, new := headerName(l[4:]), headerName(lines[i+1][4:])
+            if cur == nil || len(cur.Hunks) > 0 || (cur.Old != "" && cur.Old != old && cur.Old != DevNull) {
+                newFile(old, new)
+            } else {
+                cur.Old, cur.New = old, new
+            }
+            i++
+        case strings.HasPrefix(l, "@@"):
+            if cur == nil {
+                newFile("", "")
+            }
+            h := Hunk{}
+            if m := hunkHeader.FindStringSubmatch(l); m != nil {
+                h.OldStart, _ = strconv.Atoi(m[1])
+                h.NewStart, _ = strconv.Atoi(m[3])
+                h.Section = m[5]
+            }
+            cur.Hunks = append(cur.Hunks, h)
+            hunk = &cur.Hunks[len(cur.Hunks)-1]
+        case hunk != nil:
+            switch {
+            case l == "":
+                // Editors and chat UIs strip the space of empty context lines.
+                hunk.Lines = append(hunk.Lines, " ")
+            case l[0] == ' ' || l[0] == '+' || l[0] == '-':
+                hunk.Lines = append(hunk.Lines, l)
+            case l[0] == '\\':
+                // "\ No newline at end of file"
+            default:
+                hunk = nil
+            }
+        }
+    }
+    var out []File
+    for _, f := range files {
+        for i := range f.Hunks {
+            f.Hunks[i].Lines = trimTrailingContext(f.Hunks[i].Lines)
+        }
+        if len(f.Hunks) > 0 {
+            out = append(out, f)
+        }
+    }
+    if len(out) == 0 {
+        return nil, ErrNoHunks
+    }
+    return out, nil
+}
+
+// trimTrailingContext drops blank context lines that only came from the
+// block's trailing newlines.
+func trimTrailingContext(lines []string) []string {
+    for len(lines) > 0 && lines[len(lines)-1] == " " {
+        lines = lines[:len(lines)-1]
+    }
+    return lines
+}
+
+func headerName(s string) string {
+    if i := strings.IndexByte(s, '\t'); i >= 0 {
+        s = s[:i]
+    }
+    s = strings.TrimSpace(s)
+    if s == DevNull {
+        return s
+    }
+    if len(s) > 2 && (s[:2] == "a/" || s[:2] == "b/") {
+        return s[2:]
+    }
+    return s
+}
+
+func gitNames(s string) (string, string) {
+    if i := strings.Index(s, " b/"); i >= 0 && strings.HasPrefix(s, "a/") {
+        return s[2:i], s[i+3:]
+    }
+    f := strings.Fields(s)
+    if len(f) == 2 {
+        return headerName(f[0]), headerName(f[1])
+    }
+    return "", ""
+}
+
+// Old returns the lines the hunk expects (context and removals).
+func (h Hunk) Old() []string { return h.side('-') }
+
+// New returns the lines the hunk produces (context and additions).
+func (h Hunk) New() []string { return h.side('+') }
+
+func (h Hunk) side(keep byte) []string {
+    var out []string
+    for _, l := range h.Lines {
+        if l[0] == ' ' || l[0] == keep {
+            out = append(out, l[1:])
+        }
+    }
+    return out
+}
+
+// String renders the hunk with a header computed from its lines.
+func (h Hunk) String() string {
+    var b strings.Builder
+    if h.OldStart == 0 && len(h.Old()) > 0 {
+        b.WriteString("@@")
+    } else {
+        fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@", h.OldStart, len(h.Old()), h.NewStart, len(h.New()))
+    }
+    if h.Section != "" {
+        b.WriteString(" " + h.Section)
+    }
+    b.WriteByte('\n')
+    for _, l := range h.Lines {
+        b.WriteString(l)
+        b.WriteByte('\n')
+    }
+    return b.String()
+}
+
+// Match is where a hunk applies in a file.
+type Match struct {
+    Line   int  // 0-based index of the first line the (fuzzed) hunk covers
+    Offset int  // lines away from the position in the hunk header
+    Fuzz   int  // context lines ignored at each end
+    Loose  bool // matched only when ignoring whitespace
+    lines  []string
+}
+
+// Locate finds where h applies in lines. delta is the line shift caused by
+// earlier hunks of the same file. It prefers exact matches nearest the
+// expected position, then ignores up to maxFuzz context lines at each end,
+// then ignores whitespace differences.
+func (h Hunk) Locate(lines []string, delta, maxFuzz int) (Match, bool) {
+    m, ok := h.locate(lines, delta, maxFuzz)
+    if h.OldStart == 0 {
+        // Without line numbers there is no expected position to be off from.
+        m.Offset = 0
+    }
+    return m, ok
+}
+
+func (h Hunk) locate(lines []string, delta, maxFuzz int) (Match, bool) {
+    expected := h.OldStart - 1 + delta
+    if h.OldStart == 0 {
+        expected = 0
+    }
+    for _, loose := range []bool{false, true} {
+        for fuzz := 0; fuzz <= maxFuzz; fuzz++ {
+            body, lead, ok := h.fuzzed(fuzz)
+            if !ok {
+                break
+            }
+            old := sideOf(body, '-')
+            if len(old) == 0 {
+                at := expected + lead
+                if at < 0 {
+                    at = 0
+                }
+                if at > len(lines) {
+                    at = len(lines)
+                }
+                return Match{Line: at, Offset: at - expected - lead, Fuzz: fuzz, lines: body}, true
+            }
+            if at, ok := search(lines, old, expected+lead, loose); ok {
+                return Match{Line: at, Offset: at - expected - lead, Fuzz: fuzz, Loose: loose, lines: body}, true
+            }
+        }
+    }
+    return Match{}, false
+}
+
+// fuzzed returns the hunk lines without up to fuzz context lines at each end
+// and how many leading lines were dropped. ok is false once nothing more
+// can be dropped without losing all of the hunk's anchoring lines.
+func (h Hunk) fuzzed(fuzz int) (lines []string, lead int, ok bool) {
+    lines = h.Lines
+    lead, trail := 0, 0
+    for lead < fuzz && lead < len(lines) && lines[lead][0] == ' ' {
+        lead++
+    }
+    for trail < fuzz && trail < len(lines)-lead && lines[len(lines)-1-trail][0] == ' ' {
+        trail++
+    }
+    if fuzz > 0 && lead < fuzz && trail < fuzz {
+        // Nothing new to drop compared to the previous fuzz level.
+        return nil, 0, false
+    }
+    body := lines[lead : len(lines)-trail]
+    if fuzz > 0 && len(sideOf(body, '-')) == 0 {
+        // Dropping all context would let the hunk apply anywhere.
+        return nil, 0, false
+    }
+    return body, lead, true
+}
+
+func sideOf(lines []string, keep byte) []string {
+    var out []string
+    for _, l := range lines {
+        if l[0] == ' ' || l[0] == keep {
+            out = append(out, l[1:])
+        }
+    }
+    return out
+}
+
+// search looks for want in lines, starting at from and moving outwards.
+func search(lines, want []string, from int, loose bool) (int, bool) {
+    last := len(lines) - len(want)
+    if last < 0 {
+        return 0, false
+    }
+    if from < 0 {
+        from = 0
+    }
+    if from > last {
+        from = last
+    }
+    for d := 0; from-d >= 0 || from+d <= last; d++ {
+        if at := from - d; at >= 0 && equalAt(lines, want, at, loose) {
+            return at, true
+        }
+        if at := from + d; d > 0 && at <= last && equalAt(lines, want, at, loose) {
+            return at, true
+        }
+    }
+    return 0, false
+}
+
+func equalAt(lines, want []string, at int, loose bool) bool {
+    for i, w := range want {
+        got := lines[at+i]
+        if loose {
+            got, w = strings.Join(strings.Fields(got), " "), strings.Join(strings.Fields(w), " ")
+        }
+        if got != w {
+            return false
+        }
+    }
+    return true
+}
+
+// Apply returns lines with the hunk applied at m. Context lines keep the
+// file's own text, so loose matches do not rewrite whitespace.
+func Apply(lines []string, m Match) []string {
+    out := append([]string{}, lines[:m.Line]...)
+    pos := m.Line
+    for _, l := range m.lines {
+        switch l[0] {
+        case ' ':
+            out = append(out, lines[pos])
+            pos++
+        case '-':
+            pos++
+        case '+':
+            out = append(out, l[1:])
+        }
+    }
+    return append(out, lines[pos:]...)
+}
+
+// Delta returns how many lines applying the hunk at m adds (or removes).
+func (m Match) Delta() int {
+    n := 0
+    for _, l := range m.lines {
+        switch l[0] {
+        case '-':
+            n--
+        case '+':
+            n++
+        }
+    }
+    return n
+}
+
+// SplitLines splits content into lines and reports whether it ended in a
+// newline, so JoinLines can restore it.
+func SplitLines(content string) ([]string, bool) {
+    if content == "" {
+        return nil, true
+    }
+    eol := strings.HasSuffix(content, "\n")
+    return strings.Split(strings.TrimSuffix(content, "\n"), "\n"), eol
+}
+
+// JoinLines is the inverse of SplitLines.
+func JoinLines(lines []string, eol bool) string {
+    if len(lines) == 0 {
+        return ""
+    }
+    s := strings.Join(lines, "\n")
+    if eol {
+        s += "\n"
+    }
+    return s
+}
@@ -0,0 +1,86 @@
+// Package project loads the per-repository context file: .chatbang.md or
+// CHATBANG.md, nearest to the working directory. It holds instructions and
+// conventions prepended to the first prompt of a session, and may list
+// default attachments in the same header as prompt templates:
+//
+//     ---
+//     attach: go.mod
+//     attach: internal/**/*.go !*_test.go
+//     ---
+//     This is a Go project using cobra and chromedp. Use 4-space indents.
+package project
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+
+    "gg/internal/templates"
+)
+
+// Names are the context file names, in order of preference within one
+// directory.
+var Names = []string{".chatbang.md", "CHATBANG.md"}
+
+// Context is a parsed context file.
+type Context struct {
+    Path string
+    // Attach lists attachment specs as written; paths in them are
+    // relative to the directory of Path.
+    Attach []string
+    Body   string
+}
+
+// Find returns the context file nearest to cwd, looking in cwd and its
+// parents up to the git root. Outside a git repository only cwd is
+// checked. ok is false if there is none.
+func Find(cwd string) (path string, ok bool) {
+    root, inRepo := gitRoot(cwd)
+    if !inRepo {
+        root = cwd
+    }
+    for dir := cwd; ; dir = filepath.Dir(dir) {
+        for _, name := range Names {
+            candidate := filepath.Join(dir, name)
+            if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+                return candidate, true
+            }
+        }
+        if dir == root || filepath.Dir(dir) == dir {
+            return "", false
+        }
+    }
+}
+
+// gitRoot returns the nearest directory at or above dir holding a .git
+// entry.
+func gitRoot(dir string) (string, bool) {
+    for {
+        if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+            return dir, true
+        }
+        parent := filepath.Dir(dir)
+        if parent == dir {
+            return "", false
+        }
+        dir = parent
+    }
+}
+
+// Load reads and parses the context file at path.
+func Load(path string) (Context, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return Context{}, err
+    }
+    t, err := templates.Parse(string(data))
+    if err != nil {
+        return Context{}, fmt.Errorf("%s: %w", path, err)
+    }
+    return Context{Path: path, Attach: t.Attach, Body: t.Body}, nil
+}
+
+// Dir returns the directory attachment paths are relative to.
+func (c Context) Dir() string {
+    return filepath.Dir(c.Path)
+}
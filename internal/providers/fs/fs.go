@@ -19,6 +19,7 @@ type provider struct {
     maxBytes      int
     includeHidden bool
     allowBinary   bool
+    allowWrite    bool
 }
 
 func (p *provider) Name() string { return "fs" }
@@ -35,6 +36,7 @@ func init() {
             maxBytes:      get[int](opts, "maxBytes", 1_048_576),
             includeHidden: get[bool](opts, "includeHidden", false),
             allowBinary:   get[bool](opts, "allowBinary", false),
+            allowWrite:    get[bool](opts, "allowWrite", false),
         }
         if len(pr.roots) == 0 {
             wd, _ := os.Getwd()
@@ -143,7 +145,7 @@ func (p *provider) Handle(method string, params []byte) (any, *mcp.Error) {
         return &readResourceResult{Contents: data, MimeType: mt, Truncated: truncated, Bytes: n}, nil
 
     case "tools/list":
-        return &toolsListResult{Tools: []toolDesc{
+        tools := []toolDesc{
             {
                 Name:        "fs.list",
                 Description: "List directory entries under a path",
@@ -164,7 +166,15 @@ func (p *provider) Handle(method string, params []byte) (any, *mcp.Error) {
                 Description: "Search for text inside files",
                 InputSchema: schema(map[string]string{"root?": "string", "query": "string", "globs?": "array:string"}),
             },
-        }}, nil
+        }
+        if p.allowWrite {
+            tools = append(tools, toolDesc{
+                Name:        "fs.write",
+                Description: "Write a text file, replacing its content",
+                InputSchema: schema(map[string]string{"path": "string", "contents": "string", "mkdir?": "boolean", "dry_run?": "boolean"}),
+            })
+        }
+        return &toolsListResult{Tools: tools}, nil
 
     case "tools/call":
         var in toolCallParams
@@ -180,6 +190,8 @@ func (p *provider) Handle(method string, params []byte) (any, *mcp.Error) {
             return p.toolRead(in.Args)
         case "fs.search":
             return p.toolSearch(in.Args)
+        case "fs.write":
+            return p.toolWrite(in.Args)
         default:
             return nil, &mcp.Error{Code: -32601, Message: "unknown tool"}
         }
@@ -207,7 +219,11 @@ func (p *provider) toolList(args map[string]any) (any, *mcp.Error) {
     baseDepth := strings.Count(filepath.Clean(path), string(os.PathSeparator))
     err := filepath.WalkDir(path, func(pth string, d fs.DirEntry, err error) error {
         if err != nil { return err }
-        if !p.includeHidden && isHidden(pth) { if pth != path { return filepath.SkipDir } }
+        if !p.includeHidden && isHidden(pth) && pth != path {
+            // SkipDir on a file would skip the rest of its directory.
+            if d.IsDir() { return filepath.SkipDir }
+            return nil
+        }
         curDepth := strings.Count(filepath.Clean(pth), string(os.PathSeparator)) - baseDepth
         if curDepth > depth { if d.IsDir() { return filepath.SkipDir } ; return nil }
         if pth != path {
@@ -300,6 +316,42 @@ func (p *provider) toolSearch(args map[string]any) (any, *mcp.Error) {
     return map[string]any{"matches": matches}, nil
 }
 
+// toolWrite replaces a file's content atomically, keeping its permissions.
+// Only enabled with allow_write; the target must lie inside a root. With
+// dry_run it only checks that the write would be allowed.
+func (p *provider) toolWrite(args map[string]any) (any, *mcp.Error) {
+    if !p.allowWrite { return nil, &mcp.Error{Code: -32000, Message: "writes are disabled (set allow_write = true in mcp.toml)"} }
+    path, _ := strArg(args, "path")
+    contents, ok := strArg(args, "contents")
+    if path == "" || !ok { return nil, &mcp.Error{Code: -32602, Message: "invalid params"} }
+    if !p.writable(path) { return nil, &mcp.Error{Code: -32000, Message: "access denied"} }
+    mode := os.FileMode(0o644)
+    created := true
+    if info, err := os.Stat(path); err == nil {
+        if info.IsDir() { return nil, &mcp.Error{Code: -32002, Message: "is a directory"} }
+        mode = info.Mode().Perm()
+        created = false
+    }
+    if dryRun, _ := args["dry_run"].(bool); dryRun {
+        return map[string]any{"path": path, "bytes": len(contents), "created": created, "dry_run": true}, nil
+    }
+    dir := filepath.Dir(path)
+    if mkdir, _ := args["mkdir"].(bool); mkdir {
+        if err := os.MkdirAll(dir, 0o755); err != nil { return nil, &mcp.Error{Code: -32002, Message: err.Error()} }
+    }
+    tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*")
+    if err != nil { return nil, &mcp.Error{Code: -32002, Message: err.Error()} }
+    _, werr := tmp.WriteString(contents)
+    if cerr := tmp.Close(); werr == nil { werr = cerr }
+    if werr == nil { werr = os.Chmod(tmp.Name(), mode) }
+    if werr == nil { werr = os.Rename(tmp.Name(), path) }
+    if werr != nil {
+        os.Remove(tmp.Name())
+        return nil, &mcp.Error{Code: -32002, Message: werr.Error()}
+    }
+    return map[string]any{"path": path, "bytes": len(contents), "created": created}, nil
+}
+
 // Internals
 func (p *provider) readFile(path string) (string, string, bool, int, error) {
     fi, err := os.Stat(path)
@@ -336,6 +388,18 @@ func (p *provider) allowed(path string) bool {
     return false
 }
 
+// writable reports whether path may be written: it must be allowed if it
+// exists, otherwise its nearest existing ancestor must be.
+func (p *provider) writable(path string) bool {
+    abs, err := filepath.Abs(path)
+    if err != nil { return false }
+    if _, err := os.Lstat(abs); err == nil { return p.allowed(abs) }
+    for dir := filepath.Dir(abs); ; dir = filepath.Dir(dir) {
+        if _, err := os.Stat(dir); err == nil { return p.allowed(dir) }
+        if dir == filepath.Dir(dir) { return false }
+    }
+}
+
 func isHidden(path string) bool {
     base := filepath.Base(path)
     return strings.HasPrefix(base, ".")
@@ -0,0 +1,218 @@
+package sessions
+
+import (
+    "archive/zip"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "math"
+    "os"
+    "path"
+    "strings"
+    "time"
+)
+
+// Conversation is one conversation from a ChatGPT data export, flattened to
+// the turns of its active branch.
+type Conversation struct {
+    ID    string
+    Title string
+    Turns []Turn
+}
+
+// SessionID is the id an imported conversation is stored under. It is stable
+// across imports so re-importing the same export does not duplicate sessions.
+func (c Conversation) SessionID() string {
+    started := time.Unix(0, 0)
+    if len(c.Turns) > 0 {
+        started = c.Turns[0].Time
+    }
+    // Keep only letters and digits: the id names a file in the store.
+    short := strings.Map(func(r rune) rune {
+        if (r >= '0' && r <= '9') || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
+            return r
+        }
+        return -1
+    }, c.ID)
+    if short == "" {
+        short = Hash(c.ID + "\x00" + c.Title)
+    }
+    if len(short) > 8 {
+        short = short[:8]
+    }
+    return started.Format("20060102-150405") + "-" + short
+}
+
+// ReadChatGPTExport reads conversations from an export .zip or its
+// conversations.json.
+func ReadChatGPTExport(file string) ([]Conversation, error) {
+    if strings.EqualFold(path.Ext(file), ".zip") {
+        zr, err := zip.OpenReader(file)
+        if err != nil {
+            return nil, err
+        }
+        defer zr.Close()
+        for _, f := range zr.File {
+            if path.Base(f.Name) != "conversations.json" {
+                continue
+            }
+            rc, err := f.Open()
+            if err != nil {
+                return nil, err
+            }
+            defer rc.Close()
+            return ParseChatGPTExport(rc)
+        }
+        return nil, errors.New("conversations.json not found in archive")
+    }
+    f, err := os.Open(file)
+    if err != nil {
+        return nil, err
+    }
+    defer f.Close()
+    return ParseChatGPTExport(f)
+}
+
+// exportConversation mirrors the parts of conversations.json we use.
+type exportConversation struct {
+    ID             string                `json:"id"`
+    ConversationID string                `json:"conversation_id"`
+    Title          string                `json:"title"`
+    CreateTime     float64               `json:"create_time"`
+    CurrentNode    string                `json:"current_node"`
+    Mapping        map[string]exportNode `json:"mapping"`
+}
+
+type exportNode struct {
+    Parent  string         `json:"parent"`
+    Message *exportMessage `json:"message"`
+}
+
+type exportMessage struct {
+    Author struct {
+        Role string `json:"role"`
+    } `json:"author"`
+    CreateTime *float64 `json:"create_time"`
+    Content    struct {
+        ContentType string            `json:"content_type"`
+        Parts       []json.RawMessage `json:"parts"`
+        Text        string            `json:"text"`
+    } `json:"content"`
+    Recipient string `json:"recipient"`
+    Metadata  struct {
+        ModelSlug string `json:"model_slug"`
+        Hidden    bool   `json:"is_visually_hidden_from_conversation"`
+    } `json:"metadata"`
+}
+
+// ParseChatGPTExport decodes conversations.json. Each conversation is
+// flattened by following current_node up to the root, so edited or
+// regenerated branches that were not active are left out.
+func ParseChatGPTExport(r io.Reader) ([]Conversation, error) {
+    var raw []exportConversation
+    if err := json.NewDecoder(r).Decode(&raw); err != nil {
+        return nil, fmt.Errorf("parse conversations.json: %w", err)
+    }
+    out := make([]Conversation, 0, len(raw))
+    for _, ec := range raw {
+        id := ec.ConversationID
+        if id == "" {
+            id = ec.ID
+        }
+        conv := Conversation{ID: id, Title: ec.Title}
+        url := ""
+        if id != "" {
+            url = "https://chatgpt.com/c/" + id
+        }
+        var cur *Turn
+        for _, m := range activeBranch(ec) {
+            text := m.text()
+            if m.Metadata.Hidden || strings.TrimSpace(text) == "" {
+                continue
+            }
+            switch m.Author.Role {
+            case "user":
+                conv.Turns = append(conv.Turns, Turn{
+                    Time:            m.time(ec.CreateTime),
+                    Prompt:          text,
+                    ConversationURL: url,
+                })
+                cur = &conv.Turns[len(conv.Turns)-1]
+            case "assistant":
+                if cur == nil || (m.Recipient != "" && m.Recipient != "all") {
+                    continue
+                }
+                if cur.Answer != "" {
+                    cur.Answer += "\n\n"
+                }
+                cur.Answer += text
+                if m.Metadata.ModelSlug != "" {
+                    cur.Model = m.Metadata.ModelSlug
+                }
+            }
+        }
+        if len(conv.Turns) > 0 {
+            conv.Turns[0].Title = ec.Title
+            out = append(out, conv)
+        }
+    }
+    return out, nil
+}
+
+// activeBranch returns the messages from the root to current_node.
+func activeBranch(ec exportConversation) []*exportMessage {
+    var msgs []*exportMessage
+    seen := map[string]bool{}
+    for id := ec.CurrentNode; id != "" && !seen[id]; {
+        seen[id] = true
+        node, ok := ec.Mapping[id]
+        if !ok {
+            break
+        }
+        if node.Message != nil {
+            msgs = append(msgs, node.Message)
+        }
+        id = node.Parent
+    }
+    for i, j := 0, len(msgs)-1; i < j; i, j = i+1, j-1 {
+        msgs[i], msgs[j] = msgs[j], msgs[i]
+    }
+    return msgs
+}
+
+// text joins the textual parts of a message; images and other non-text
+// parts become placeholders.
+func (m *exportMessage) text() string {
+    switch m.Content.ContentType {
+    case "text", "multimodal_text":
+    default:
+        return ""
+    }
+    var parts []string
+    for _, p := range m.Content.Parts {
+        var s string
+        if json.Unmarshal(p, &s) == nil {
+            if s != "" {
+                parts = append(parts, s)
+            }
+            continue
+        }
+        var obj struct {
+            ContentType string `json:"content_type"`
+        }
+        if json.Unmarshal(p, &obj) == nil && obj.ContentType != "" {
+            parts = append(parts, "["+strings.TrimSuffix(obj.ContentType, "_asset_pointer")+"]")
+        }
+    }
+    return strings.Join(parts, "\n")
+}
+
+func (m *exportMessage) time(fallback float64) time.Time {
+    secs := fallback
+    if m.CreateTime != nil && *m.CreateTime > 0 {
+        secs = *m.CreateTime
+    }
+    whole, frac := math.Modf(secs)
+    return time.Unix(int64(whole), int64(frac*1e9)).UTC()
+}
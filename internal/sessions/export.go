@@ -0,0 +1,339 @@
+package sessions
+
+import (
+    "encoding/json"
+    "fmt"
+    "html/template"
+    "io"
+    "os"
+    "path/filepath"
+    "strings"
+
+    "github.com/alecthomas/chroma"
+    chromahtml "github.com/alecthomas/chroma/formatters/html"
+    "github.com/alecthomas/chroma/lexers"
+    "github.com/alecthomas/chroma/styles"
+    "github.com/gomarkdown/markdown"
+    "github.com/gomarkdown/markdown/ast"
+    mdhtml "github.com/gomarkdown/markdown/html"
+    "github.com/gomarkdown/markdown/parser"
+)
+
+// Formats lists the supported export formats.
+var Formats = []string{"md", "html", "json"}
+
+// FormatForPath picks an export format from a file extension, defaulting to md.
+func FormatForPath(path string) string {
+    switch strings.ToLower(filepath.Ext(path)) {
+    case ".html", ".htm":
+        return "html"
+    case ".json":
+        return "json"
+    }
+    return "md"
+}
+
+// ValidFormat reports an error unless format is a supported export format.
+func ValidFormat(format string) error {
+    switch format {
+    case "md", "markdown", "html", "json":
+        return nil
+    }
+    return unknownFormat(format)
+}
+
+func unknownFormat(format string) error {
+    return fmt.Errorf("unknown format %q (want %s)", format, strings.Join(Formats, ", "))
+}
+
+// ExportFile writes turns of session id to path. The format is checked
+// first, and the file is written to a temporary file next to path and
+// renamed over it only on success, so a failed export leaves an existing
+// file untouched. A new file is private (0600), like the session store; an
+// existing one keeps its mode.
+func ExportFile(path, id string, turns []Turn, format string) error {
+    if err := ValidFormat(format); err != nil {
+        return err
+    }
+    f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+    if err != nil {
+        return err
+    }
+    tmp := f.Name()
+    err = Export(f, id, turns, format)
+    if info, serr := os.Stat(path); err == nil && serr == nil {
+        err = f.Chmod(info.Mode().Perm())
+    }
+    if cerr := f.Close(); err == nil {
+        err = cerr
+    }
+    if err == nil {
+        err = os.Rename(tmp, path)
+    }
+    if err != nil {
+        os.Remove(tmp)
+    }
+    return err
+}
+
+// Export writes turns of session id to w as md, html or json.
+func Export(w io.Writer, id string, turns []Turn, format string) error {
+    switch format {
+    case "md", "markdown":
+        return exportMarkdown(w, id, turns)
+    case "html":
+        return exportHTML(w, id, turns)
+    case "json":
+        enc := json.NewEncoder(w)
+        enc.SetIndent("", "  ")
+        return enc.Encode(struct {
+            ID    string `json:"id"`
+            Turns []Turn `json:"turns"`
+        }{id, turns})
+    default:
+        return unknownFormat(format)
+    }
+}
+
+func exportMarkdown(w io.Writer, id string, turns []Turn) error {
+    var b strings.Builder
+    fmt.Fprintf(&b, "# %s\n\n", exportTitle(id, turns))
+    fmt.Fprintf(&b, "_Session %s", id)
+    if url := conversationURL(turns); url != "" {
+        fmt.Fprintf(&b, " · [ChatGPT conversation](%s)", url)
+    }
+    b.WriteString("_\n")
+    for i, t := range turns {
+        fmt.Fprintf(&b, "\n## Turn %d\n\n", i+1)
+        fmt.Fprintf(&b, "_%s", t.Time.Local().Format("2006-01-02 15:04"))
+        if t.Model != "" {
+            fmt.Fprintf(&b, " · %s", t.Model)
+        }
+        b.WriteString("_\n\n")
+        if len(t.Attachments) > 0 {
+            fmt.Fprintf(&b, "<details>\n<summary>Attachments (%d)</summary>\n\n", len(t.Attachments))
+            for _, at := range t.Attachments {
+                fmt.Fprintf(&b, "- `%s`", at.Path)
+                if at.SHA256 != "" {
+                    fmt.Fprintf(&b, " (sha256 %s)", shortHash(at.SHA256))
+                }
+                b.WriteString("\n")
+                if at.Content != "" {
+                    fence := fenceFor(at.Content)
+                    fmt.Fprintf(&b, "\n%s%s\n%s\n%s\n\n", fence, langForPath(at.Path), strings.TrimRight(at.Content, "\n"), fence)
+                }
+            }
+            b.WriteString("\n</details>\n\n")
+        }
+        b.WriteString("**You:**\n\n")
+        b.WriteString(strings.TrimSpace(t.Prompt))
+        b.WriteString("\n\n**ChatGPT:**\n\n")
+        b.WriteString(strings.TrimSpace(t.Answer))
+        b.WriteString("\n")
+    }
+    _, err := io.WriteString(w, b.String())
+    return err
+}
+
+var htmlPage = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; line-height: 1.5; color: #1f2328; max-width: 860px; margin: 2em auto; padding: 0 1em; }
+header p, .meta { color: #656d76; font-size: 0.9em; }
+.turn { border-top: 1px solid #d0d7de; padding-top: 1em; margin-top: 1.5em; }
+.prompt { background: #f6f8fa; border-left: 4px solid #0969da; padding: 0.5em 1em; white-space: pre-wrap; }
+.role { font-weight: 600; margin: 1em 0 0.3em; }
+pre { padding: 0.8em; overflow-x: auto; border-radius: 6px; font-size: 0.85em; }
+code { font-family: ui-monospace, SFMono-Regular, Menlo, Consolas, monospace; }
+:not(pre) > code { background: #eff1f3; padding: 0.1em 0.3em; border-radius: 4px; }
+.lang { font-size: 0.75em; color: #656d76; margin-bottom: -0.6em; }
+details { margin: 0.5em 0; }
+summary { cursor: pointer; color: #656d76; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #d0d7de; padding: 0.3em 0.6em; }
+{{.CSS}}
+</style>
+</head>
+<body>
+<header>
+<h1>{{.Title}}</h1>
+<p>Session {{.ID}}{{if .URL}} · <a href="{{.URL}}">ChatGPT conversation</a>{{end}}</p>
+</header>
+{{range .Turns}}<section class="turn" id="turn-{{.N}}">
+<div class="meta">Turn {{.N}} · {{.Time}}{{if .Model}} · {{.Model}}{{end}}</div>
+{{if .Attachments}}<details>
+<summary>Attachments ({{len .Attachments}})</summary>
+<ul>
+{{range .Attachments}}<li><code>{{.Path}}</code>{{if .Hash}} <span class="meta">sha256 {{.Hash}}</span>{{end}}{{if .Content}}
+{{.Content}}{{end}}</li>
+{{end}}</ul>
+</details>
+{{end}}<div class="role">You</div>
+<div class="prompt">{{.Prompt}}</div>
+<div class="role">ChatGPT</div>
+<div class="answer">
+{{.Answer}}
+</div>
+</section>
+{{end}}</body>
+</html>
+`))
+
+type htmlAttachment struct {
+    Path, Hash string
+    Content    template.HTML
+}
+
+type htmlTurn struct {
+    N                   int
+    Time, Model, Prompt string
+    Attachments         []htmlAttachment
+    Answer              template.HTML
+}
+
+func exportHTML(w io.Writer, id string, turns []Turn) error {
+    style := styles.Get("github")
+    formatter := chromahtml.New(chromahtml.WithClasses(true), chromahtml.TabWidth(4))
+    var css strings.Builder
+    if err := formatter.WriteCSS(&css, style); err != nil {
+        return err
+    }
+    page := struct {
+        Title, ID, URL string
+        CSS            template.CSS
+        Turns          []htmlTurn
+    }{
+        Title: exportTitle(id, turns),
+        ID:    id,
+        URL:   conversationURL(turns),
+        CSS:   template.CSS(css.String()),
+    }
+    for i, t := range turns {
+        ht := htmlTurn{
+            N:      i + 1,
+            Time:   t.Time.Local().Format("2006-01-02 15:04"),
+            Model:  t.Model,
+            Prompt: strings.TrimSpace(t.Prompt),
+            Answer: template.HTML(renderHTML(t.Answer, formatter, style)),
+        }
+        for _, at := range t.Attachments {
+            ha := htmlAttachment{Path: at.Path, Hash: shortHash(at.SHA256)}
+            if at.Content != "" {
+                var b strings.Builder
+                highlight(&b, at.Content, langForPath(at.Path), formatter, style)
+                ha.Content = template.HTML(b.String())
+            }
+            ht.Attachments = append(ht.Attachments, ha)
+        }
+        page.Turns = append(page.Turns, ht)
+    }
+    return htmlPage.Execute(w, page)
+}
+
+// renderHTML converts Markdown to HTML, highlighting fenced code blocks.
+// Raw HTML in the source is dropped.
+func renderHTML(md string, formatter *chromahtml.Formatter, style *chroma.Style) string {
+    renderer := mdhtml.NewRenderer(mdhtml.RendererOptions{
+        Flags: mdhtml.CommonFlags | mdhtml.SkipHTML | mdhtml.HrefTargetBlank,
+        RenderNodeHook: func(w io.Writer, node ast.Node, entering bool) (ast.WalkStatus, bool) {
+            cb, ok := node.(*ast.CodeBlock)
+            if !ok {
+                return ast.GoToNext, false
+            }
+            lang := strings.Fields(string(cb.Info) + " ")
+            name := ""
+            if len(lang) > 0 {
+                name = lang[0]
+                fmt.Fprintf(w, "<div class=\"lang\">%s</div>\n", template.HTMLEscapeString(name))
+            }
+            highlight(w, string(cb.Literal), name, formatter, style)
+            return ast.GoToNext, true
+        },
+    })
+    p := parser.NewWithExtensions(parser.CommonExtensions)
+    return string(markdown.ToHTML([]byte(md), p, renderer))
+}
+
+// highlight writes code as a highlighted <pre> block, falling back to
+// escaped plain text.
+func highlight(w io.Writer, code, lang string, formatter *chromahtml.Formatter, style *chroma.Style) {
+    lexer := lexers.Get(lang)
+    if lexer == nil {
+        lexer = lexers.Analyse(code)
+    }
+    if lexer == nil {
+        lexer = lexers.Fallback
+    }
+    it, err := chroma.Coalesce(lexer).Tokenise(nil, code)
+    if err == nil {
+        err = formatter.Format(w, style, it)
+    }
+    if err != nil {
+        fmt.Fprintf(w, "<pre><code>%s</code></pre>\n", template.HTMLEscapeString(code))
+    }
+}
+
+func exportTitle(id string, turns []Turn) string {
+    if len(turns) > 0 {
+        if t := sessionTitle(turns); t != "" {
+            return t
+        }
+    }
+    return "Session " + id
+}
+
+func conversationURL(turns []Turn) string {
+    url := ""
+    for _, t := range turns {
+        if t.ConversationURL != "" {
+            url = t.ConversationURL
+        }
+    }
+    return url
+}
+
+func shortHash(h string) string {
+    if len(h) > 12 {
+        return h[:12]
+    }
+    return h
+}
+
+// fenceFor returns a backtick fence longer than any run inside content.
+func fenceFor(content string) string {
+    fence := "```"
+    for strings.Contains(content, fence) {
+        fence += "`"
+    }
+    return fence
+}
+
+// langForPath guesses a fence language tag from a file extension.
+func langForPath(path string) string {
+    ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+    switch ext {
+    case "":
+        return ""
+    case "yml":
+        return "yaml"
+    case "md":
+        return "markdown"
+    case "sh", "bash", "zsh":
+        return "bash"
+    case "py":
+        return "python"
+    case "js", "mjs", "cjs":
+        return "javascript"
+    case "ts", "tsx":
+        return "typescript"
+    case "rs":
+        return "rust"
+    case "rb":
+        return "ruby"
+    }
+    return ext
+}
@@ -0,0 +1,322 @@
+package sessions
+
+import (
+    "encoding/json"
+    "errors"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "time"
+    "unicode"
+)
+
+const indexVersion = 2
+
+// index is an inverted index from lowercase terms to the turns containing
+// them. Stamps records the transcript size and modification time each
+// session was indexed at, so a session appended to or rewritten (even to the
+// same size) is re-indexed on the next search.
+type index struct {
+    Version int                         `json:"version"`
+    Stamps  map[string]stamp            `json:"stamps"`
+    Terms   map[string]map[string][]int `json:"terms"`
+}
+
+// stamp identifies a version of a transcript file.
+type stamp struct {
+    Size    int64 `json:"size"`
+    ModTime int64 `json:"mtime"` // Unix nanoseconds
+}
+
+func stampOf(info os.FileInfo) stamp {
+    return stamp{Size: info.Size(), ModTime: info.ModTime().UnixNano()}
+}
+
+// Hit is a search result: one turn of a session.
+type Hit struct {
+    ID      string
+    Turn    int // 1-based
+    Time    time.Time
+    Score   int
+    Snippet string
+}
+
+func (s *Store) indexPath() string { return filepath.Join(s.dir, "index.json") }
+
+func (s *Store) loadIndex() *index {
+    idx := &index{Version: indexVersion, Stamps: map[string]stamp{}, Terms: map[string]map[string][]int{}}
+    data, err := os.ReadFile(s.indexPath())
+    if err != nil {
+        return idx
+    }
+    var disk index
+    if json.Unmarshal(data, &disk) != nil || disk.Version != indexVersion || disk.Stamps == nil || disk.Terms == nil {
+        return idx
+    }
+    return &disk
+}
+
+func (s *Store) saveIndex(idx *index) error {
+    data, err := json.Marshal(idx)
+    if err != nil {
+        return err
+    }
+    tmp := s.indexPath() + ".tmp"
+    if err := os.WriteFile(tmp, data, 0o600); err != nil {
+        return err
+    }
+    return os.Rename(tmp, s.indexPath())
+}
+
+// drop removes a session from the index.
+func (idx *index) drop(id string) {
+    delete(idx.Stamps, id)
+    for term, docs := range idx.Terms {
+        if _, ok := docs[id]; ok {
+            delete(docs, id)
+            if len(docs) == 0 {
+                delete(idx.Terms, term)
+            }
+        }
+    }
+}
+
+// add indexes the turns of a session.
+func (idx *index) add(id string, turns []Turn, st stamp) {
+    idx.Stamps[id] = st
+    for i, t := range turns {
+        seen := map[string]bool{}
+        for _, term := range tokenize(t.Prompt + "\n" + t.Answer) {
+            if seen[term] {
+                continue
+            }
+            seen[term] = true
+            docs := idx.Terms[term]
+            if docs == nil {
+                docs = map[string][]int{}
+                idx.Terms[term] = docs
+            }
+            docs[id] = append(docs[id], i)
+        }
+    }
+}
+
+// refresh re-indexes session id from disk, or drops it if it is gone.
+func (s *Store) refresh(idx *index, id string) error {
+    idx.drop(id)
+    info, err := os.Stat(s.path(id))
+    if errors.Is(err, os.ErrNotExist) {
+        return nil
+    }
+    if err != nil {
+        return err
+    }
+    turns, err := s.load(id)
+    if err != nil {
+        return err
+    }
+    idx.add(id, turns, stampOf(info))
+    return nil
+}
+
+// reindex re-indexes session id right away; used when it is removed.
+func (s *Store) reindex(id string) error {
+    idx := s.loadIndex()
+    if err := s.refresh(idx, id); err != nil {
+        return err
+    }
+    return s.saveIndex(idx)
+}
+
+// sync brings the index up to date with the transcripts on disk.
+func (s *Store) sync() (*index, error) {
+    idx := s.loadIndex()
+    ids, err := s.IDs()
+    if err != nil {
+        return nil, err
+    }
+    changed := false
+    present := map[string]bool{}
+    for _, id := range ids {
+        present[id] = true
+        info, err := os.Stat(s.path(id))
+        if err != nil {
+            continue
+        }
+        if st, ok := idx.Stamps[id]; ok && st == stampOf(info) {
+            continue
+        }
+        if err := s.refresh(idx, id); err != nil {
+            continue
+        }
+        changed = true
+    }
+    for id := range idx.Stamps {
+        if !present[id] {
+            idx.drop(id)
+            changed = true
+        }
+    }
+    if changed {
+        if err := s.saveIndex(idx); err != nil {
+            return idx, err
+        }
+    }
+    return idx, nil
+}
+
+// Reindex brings the search index up to date after bulk writes with Put.
+func (s *Store) Reindex() error {
+    _, err := s.sync()
+    return err
+}
+
+// Search returns turns containing every term of query, best matches first.
+// Terms ending in * match by prefix.
+func (s *Store) Search(query string, limit int) ([]Hit, error) {
+    terms := queryTerms(query)
+    if len(terms) == 0 {
+        return nil, nil
+    }
+    idx, err := s.sync()
+    if err != nil {
+        return nil, err
+    }
+
+    type key struct {
+        id   string
+        turn int
+    }
+    var matched map[key]int
+    for _, q := range terms {
+        cur := map[key]int{}
+        for term, docs := range idx.Terms {
+            if term != q.text && !(q.prefix && strings.HasPrefix(term, q.text)) {
+                continue
+            }
+            for id, turns := range docs {
+                for _, t := range turns {
+                    cur[key{id, t}]++
+                }
+            }
+        }
+        if matched == nil {
+            matched = cur
+            continue
+        }
+        for k, n := range matched {
+            if c, ok := cur[k]; ok {
+                matched[k] = n + c
+            } else {
+                delete(matched, k)
+            }
+        }
+    }
+
+    cache := map[string][]Turn{}
+    var hits []Hit
+    for k, score := range matched {
+        turns, ok := cache[k.id]
+        if !ok {
+            turns, _ = s.load(k.id)
+            cache[k.id] = turns
+        }
+        if k.turn >= len(turns) {
+            continue
+        }
+        t := turns[k.turn]
+        hits = append(hits, Hit{
+            ID:      k.id,
+            Turn:    k.turn + 1,
+            Time:    t.Time,
+            Score:   score + countOccurrences(t, terms),
+            Snippet: snippet(t.Prompt+"\n"+t.Answer, terms),
+        })
+    }
+    sort.Slice(hits, func(i, j int) bool {
+        if hits[i].Score != hits[j].Score {
+            return hits[i].Score > hits[j].Score
+        }
+        return hits[i].Time.After(hits[j].Time)
+    })
+    if limit > 0 && len(hits) > limit {
+        hits = hits[:limit]
+    }
+    return hits, nil
+}
+
+type queryTerm struct {
+    text   string
+    prefix bool
+}
+
+func queryTerms(query string) []queryTerm {
+    var out []queryTerm
+    for _, f := range strings.Fields(query) {
+        prefix := strings.HasSuffix(f, "*")
+        for _, t := range tokenize(f) {
+            out = append(out, queryTerm{text: t, prefix: prefix})
+        }
+    }
+    return out
+}
+
+// tokenize lowercases text and splits it into letter/digit runs of at least
+// two characters.
+func tokenize(text string) []string {
+    fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+        return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+    })
+    out := fields[:0]
+    for _, f := range fields {
+        if len([]rune(f)) >= 2 {
+            out = append(out, f)
+        }
+    }
+    return out
+}
+
+// countOccurrences is a tie-breaker: how often the query terms occur in t.
+func countOccurrences(t Turn, terms []queryTerm) int {
+    text := strings.ToLower(t.Prompt + "\n" + t.Answer)
+    n := 0
+    for _, q := range terms {
+        n += strings.Count(text, q.text)
+    }
+    return n
+}
+
+// snippet returns a single line of text around the first query term.
+func snippet(text string, terms []queryTerm) string {
+    lower := strings.ToLower(text)
+    at := -1
+    for _, q := range terms {
+        if i := strings.Index(lower, q.text); i >= 0 && (at < 0 || i < at) {
+            at = i
+        }
+    }
+    if at < 0 {
+        at = 0
+    }
+    runes := []rune(text)
+    pos := len([]rune(lower[:at]))
+    if pos > len(runes) {
+        pos = len(runes)
+    }
+    start, end := pos-30, pos+50
+    if start < 0 {
+        start = 0
+    }
+    if end > len(runes) {
+        end = len(runes)
+    }
+    s := strings.Join(strings.Fields(string(runes[start:end])), " ")
+    if start > 0 {
+        s = "..." + s
+    }
+    if end < len(runes) {
+        s += "..."
+    }
+    return s
+}
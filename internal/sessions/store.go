@@ -0,0 +1,279 @@
+// Package sessions stores chat transcripts locally, one JSONL file per REPL
+// session under ~/.config/chatbang/sessions, with an offline full-text index.
+package sessions
+
+import (
+    "bufio"
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "time"
+)
+
+// Turn is one prompt/answer exchange.
+type Turn struct {
+    Time            time.Time    `json:"time"`
+    Prompt          string       `json:"prompt"`
+    Attachments     []Attachment `json:"attachments,omitempty"`
+    Answer          string       `json:"answer"`
+    Model           string       `json:"model,omitempty"`
+    ConversationURL string       `json:"conversation_url,omitempty"`
+    // Title is set on the first turn of imported conversations.
+    Title string `json:"title,omitempty"`
+}
+
+// Attachment records a file sent with a turn. Content is kept only when
+// the source is not a local file (e.g. imported transcripts).
+type Attachment struct {
+    Path    string `json:"path"`
+    SHA256  string `json:"sha256,omitempty"`
+    Content string `json:"content,omitempty"`
+}
+
+// Summary describes a stored session for listings.
+type Summary struct {
+    ID              string
+    Title           string
+    Started         time.Time
+    Updated         time.Time
+    Turns           int
+    ConversationURL string
+}
+
+// ErrNotFound is returned when no session matches an id.
+var ErrNotFound = errors.New("session not found")
+
+// Store is a directory of session transcripts.
+type Store struct {
+    dir string
+}
+
+// Open returns the store at dir, creating the directory if needed.
+func Open(dir string) (*Store, error) {
+    if err := os.MkdirAll(dir, 0o700); err != nil {
+        return nil, err
+    }
+    return &Store{dir: dir}, nil
+}
+
+// Dir returns the store directory.
+func (s *Store) Dir() string { return s.dir }
+
+// NewID returns a sortable, unique session id such as 20261019-153000-1a2b.
+func NewID(t time.Time) string {
+    var b [2]byte
+    _, _ = rand.Read(b[:])
+    return t.Format("20060102-150405") + "-" + hex.EncodeToString(b[:])
+}
+
+// Hash returns the hex SHA-256 of content, as stored for attachments.
+func Hash(content string) string {
+    sum := sha256.Sum256([]byte(content))
+    return hex.EncodeToString(sum[:])
+}
+
+func (s *Store) path(id string) string { return filepath.Join(s.dir, id+".jsonl") }
+
+// checkID rejects ids that would not name a file directly in the store.
+func checkID(id string) error {
+    if id == "" || id == "." || strings.Contains(id, "..") || strings.ContainsAny(id, `/\`) {
+        return fmt.Errorf("invalid session id %q", id)
+    }
+    return nil
+}
+
+// Append adds a turn to session id. Like Put, it leaves the search index
+// alone: the transcript's new size marks it for re-indexing on the next
+// search, so a turn costs no more than writing it.
+func (s *Store) Append(id string, t Turn) error {
+    if err := checkID(id); err != nil {
+        return err
+    }
+    line, err := json.Marshal(t)
+    if err != nil {
+        return err
+    }
+    f, err := os.OpenFile(s.path(id), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+    if err != nil {
+        return err
+    }
+    if _, err := f.Write(append(line, '\n')); err != nil {
+        f.Close()
+        return err
+    }
+    return f.Close()
+}
+
+// Put writes a whole session, replacing any existing transcript with that
+// id. The search index catches up on the next search.
+func (s *Store) Put(id string, turns []Turn) error {
+    if err := checkID(id); err != nil {
+        return err
+    }
+    var b []byte
+    for _, t := range turns {
+        line, err := json.Marshal(t)
+        if err != nil {
+            return err
+        }
+        b = append(append(b, line...), '\n')
+    }
+    tmp := s.path(id) + ".tmp"
+    if err := os.WriteFile(tmp, b, 0o600); err != nil {
+        return err
+    }
+    return os.Rename(tmp, s.path(id))
+}
+
+// Exists reports whether a session with exactly this id is stored.
+func (s *Store) Exists(id string) bool {
+    if checkID(id) != nil {
+        return false
+    }
+    _, err := os.Stat(s.path(id))
+    return err == nil
+}
+
+// Load returns the turns of session id (an id or unique id prefix).
+func (s *Store) Load(id string) ([]Turn, error) {
+    full, err := s.Resolve(id)
+    if err != nil {
+        return nil, err
+    }
+    return s.load(full)
+}
+
+func (s *Store) load(id string) ([]Turn, error) {
+    f, err := os.Open(s.path(id))
+    if err != nil {
+        if errors.Is(err, os.ErrNotExist) {
+            return nil, ErrNotFound
+        }
+        return nil, err
+    }
+    defer f.Close()
+    var turns []Turn
+    sc := bufio.NewScanner(f)
+    sc.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+    for sc.Scan() {
+        if len(strings.TrimSpace(sc.Text())) == 0 {
+            continue
+        }
+        var t Turn
+        if err := json.Unmarshal(sc.Bytes(), &t); err != nil {
+            return turns, fmt.Errorf("%s: %w", id, err)
+        }
+        turns = append(turns, t)
+    }
+    return turns, sc.Err()
+}
+
+// IDs returns all session ids, oldest first.
+func (s *Store) IDs() ([]string, error) {
+    entries, err := os.ReadDir(s.dir)
+    if err != nil {
+        return nil, err
+    }
+    var ids []string
+    for _, e := range entries {
+        if name := e.Name(); !e.IsDir() && strings.HasSuffix(name, ".jsonl") {
+            ids = append(ids, strings.TrimSuffix(name, ".jsonl"))
+        }
+    }
+    sort.Strings(ids)
+    return ids, nil
+}
+
+// Resolve expands a unique id prefix to a full id.
+func (s *Store) Resolve(prefix string) (string, error) {
+    if prefix == "" {
+        return "", ErrNotFound
+    }
+    ids, err := s.IDs()
+    if err != nil {
+        return "", err
+    }
+    var match string
+    for _, id := range ids {
+        if id == prefix {
+            return id, nil
+        }
+        if strings.HasPrefix(id, prefix) {
+            if match != "" {
+                return "", fmt.Errorf("ambiguous session id %q", prefix)
+            }
+            match = id
+        }
+    }
+    if match == "" {
+        return "", ErrNotFound
+    }
+    return match, nil
+}
+
+// List summarizes all sessions, newest first.
+func (s *Store) List() ([]Summary, error) {
+    ids, err := s.IDs()
+    if err != nil {
+        return nil, err
+    }
+    out := make([]Summary, 0, len(ids))
+    for i := len(ids) - 1; i >= 0; i-- {
+        turns, err := s.load(ids[i])
+        if err != nil || len(turns) == 0 {
+            continue
+        }
+        sum := Summary{
+            ID:      ids[i],
+            Title:   sessionTitle(turns),
+            Started: turns[0].Time,
+            Updated: turns[len(turns)-1].Time,
+            Turns:   len(turns),
+        }
+        for _, t := range turns {
+            if t.ConversationURL != "" {
+                sum.ConversationURL = t.ConversationURL
+            }
+        }
+        out = append(out, sum)
+    }
+    return out, nil
+}
+
+// Remove deletes session id (or unique prefix) and its index entries.
+func (s *Store) Remove(id string) (string, error) {
+    full, err := s.Resolve(id)
+    if err != nil {
+        return "", err
+    }
+    if err := os.Remove(s.path(full)); err != nil {
+        return "", err
+    }
+    return full, s.reindex(full)
+}
+
+func sessionTitle(turns []Turn) string {
+    if turns[0].Title != "" {
+        return Title(turns[0].Title)
+    }
+    return Title(turns[0].Prompt)
+}
+
+// Title returns the first line of a prompt, shortened for listings.
+func Title(prompt string) string {
+    line := strings.TrimSpace(prompt)
+    if i := strings.IndexByte(line, '\n'); i >= 0 {
+        line = line[:i]
+    }
+    if r := []rune(line); len(r) > 60 {
+        line = string(r[:57]) + "..."
+    }
+    return line
+}
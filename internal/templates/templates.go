@@ -0,0 +1,272 @@
+// Package templates loads reusable prompt templates: Markdown files with
+// {{var}} placeholders and an optional header of directives.
+//
+// A template looks like:
+//
+//     ---
+//     description: Review a Go file
+//     attach: {{file}}
+//     ---
+//     Review {{file}} for {{focus|bugs and readability}}.
+//
+// {{name|default}} falls back to default when name is not given; a
+// placeholder without a default is required. Header directives are
+// "description" (shown in listings) and "attach" (a file to attach, may
+// repeat and may use placeholders).
+package templates
+
+import (
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strings"
+)
+
+// Ext is the file extension of template files.
+const Ext = ".md"
+
+// ErrNotFound is returned by Find when no directory has the template.
+var ErrNotFound = errors.New("template not found")
+
+// Template is one parsed template file.
+type Template struct {
+    Name        string
+    Path        string
+    Description string
+    Attach      []string // may contain placeholders
+    Body        string
+}
+
+// Var is a placeholder used by a template.
+type Var struct {
+    Name       string
+    Default    string
+    HasDefault bool
+}
+
+// Rendered is a template with its variables filled in.
+type Rendered struct {
+    Prompt string
+    Attach []string
+}
+
+var placeholder = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_.-]*)\s*(?:\|([^}]*))?\}\}`)
+
+// Dirs returns the template directories, highest precedence first: the
+// nearest .chatbang/templates at or above cwd (stopping at the git root),
+// then configDir/templates.
+func Dirs(configDir, cwd string) []string {
+    var dirs []string
+    for dir := cwd; dir != ""; {
+        candidate := filepath.Join(dir, ".chatbang", "templates")
+        if info, err := os.Stat(candidate); err == nil && info.IsDir() {
+            dirs = append(dirs, candidate)
+            break
+        }
+        if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+            break
+        }
+        parent := filepath.Dir(dir)
+        if parent == dir {
+            break
+        }
+        dir = parent
+    }
+    return append(dirs, filepath.Join(configDir, "templates"))
+}
+
+// List returns the templates in dirs, sorted by name. A name found in an
+// earlier directory hides the same name in later ones.
+func List(dirs []string) ([]Template, error) {
+    seen := map[string]bool{}
+    var out []Template
+    for _, dir := range dirs {
+        entries, err := os.ReadDir(dir)
+        if err != nil {
+            continue
+        }
+        for _, e := range entries {
+            name := strings.TrimSuffix(e.Name(), Ext)
+            if e.IsDir() || name == e.Name() || strings.HasPrefix(name, ".") || seen[name] {
+                continue
+            }
+            t, err := Load(filepath.Join(dir, e.Name()))
+            if err != nil {
+                return nil, err
+            }
+            seen[name] = true
+            out = append(out, t)
+        }
+    }
+    sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+    return out, nil
+}
+
+// Find loads the template called name from the first directory that has it.
+func Find(dirs []string, name string) (Template, error) {
+    if name == "" || strings.ContainsAny(name, `/\`) {
+        return Template{}, fmt.Errorf("invalid template name %q", name)
+    }
+    for _, dir := range dirs {
+        path := filepath.Join(dir, name+Ext)
+        if _, err := os.Stat(path); err == nil {
+            return Load(path)
+        }
+    }
+    return Template{}, fmt.Errorf("%w: %s", ErrNotFound, name)
+}
+
+// Load reads and parses a template file.
+func Load(path string) (Template, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return Template{}, err
+    }
+    t, err := Parse(string(data))
+    if err != nil {
+        return Template{}, fmt.Errorf("%s: %w", path, err)
+    }
+    t.Name = strings.TrimSuffix(filepath.Base(path), Ext)
+    t.Path = path
+    return t, nil
+}
+
+// Parse splits a template into its header directives and body.
+func Parse(text string) (Template, error) {
+    var t Template
+    text = strings.ReplaceAll(text, "\r\n", "\n")
+    if rest, ok := strings.CutPrefix(text, "---\n"); ok {
+        header, body, found := strings.Cut(rest, "\n---\n")
+        if !found {
+            if h, ok := strings.CutSuffix(rest, "\n---"); ok {
+                header, body, found = h, "", true
+            }
+        }
+        if !found {
+            return t, errors.New("header opened with --- is not closed")
+        }
+        for i, line := range strings.Split(header, "\n") {
+            line = strings.TrimSpace(line)
+            if line == "" || strings.HasPrefix(line, "#") {
+                continue
+            }
+            key, value, ok := strings.Cut(line, ":")
+            if !ok {
+                return t, fmt.Errorf("header line %d: expected key: value", i+2)
+            }
+            value = strings.TrimSpace(value)
+            switch strings.ToLower(strings.TrimSpace(key)) {
+            case "description":
+                t.Description = value
+            case "attach":
+                if value != "" {
+                    t.Attach = append(t.Attach, value)
+                }
+            default:
+                return t, fmt.Errorf("header line %d: unknown directive %q", i+2, strings.TrimSpace(key))
+            }
+        }
+        text = body
+    }
+    t.Body = strings.TrimSpace(text)
+    return t, nil
+}
+
+// Vars returns the placeholders of the body and attach directives in order
+// of first use. The first default given for a name wins.
+func (t Template) Vars() []Var {
+    var out []Var
+    index := map[string]int{}
+    for _, s := range append([]string{t.Body}, t.Attach...) {
+        for _, m := range placeholder.FindAllStringSubmatchIndex(s, -1) {
+            name := s[m[2]:m[3]]
+            v := Var{Name: name}
+            if m[4] >= 0 {
+                v.Default, v.HasDefault = s[m[4]:m[5]], true
+            }
+            if i, ok := index[name]; ok {
+                if !out[i].HasDefault && v.HasDefault {
+                    out[i] = v
+                }
+                continue
+            }
+            index[name] = len(out)
+            out = append(out, v)
+        }
+    }
+    return out
+}
+
+// Render fills in the placeholders. Every variable without a default must
+// be given, and every given variable must be used.
+func (t Template) Render(vars map[string]string) (Rendered, error) {
+    var missing []string
+    used := map[string]bool{}
+    defaults := map[string]string{}
+    for _, v := range t.Vars() {
+        used[v.Name] = true
+        if v.HasDefault {
+            defaults[v.Name] = v.Default
+        } else if _, ok := vars[v.Name]; !ok {
+            missing = append(missing, v.Name)
+        }
+    }
+    if len(missing) > 0 {
+        return Rendered{}, fmt.Errorf("template %s needs %s", t.Name, strings.Join(missing, ", "))
+    }
+    var unknown []string
+    for name := range vars {
+        if !used[name] {
+            unknown = append(unknown, name)
+        }
+    }
+    if len(unknown) > 0 {
+        sort.Strings(unknown)
+        return Rendered{}, fmt.Errorf("template %s has no variable %s", t.Name, strings.Join(unknown, ", "))
+    }
+    fill := func(s string) string {
+        return placeholder.ReplaceAllStringFunc(s, func(m string) string {
+            name := placeholder.FindStringSubmatch(m)[1]
+            if v, ok := vars[name]; ok {
+                return v
+            }
+            return defaults[name]
+        })
+    }
+    r := Rendered{Prompt: fill(t.Body)}
+    for _, a := range t.Attach {
+        if p := strings.TrimSpace(fill(a)); p != "" {
+            r.Attach = append(r.Attach, p)
+        }
+    }
+    return r, nil
+}
+
+// Usage renders a template's variables, e.g. "review file=... [focus=...]".
+func (t Template) Usage() string {
+    parts := []string{t.Name}
+    for _, v := range t.Vars() {
+        if v.HasDefault {
+            parts = append(parts, "["+v.Name+"=...]")
+        } else {
+            parts = append(parts, v.Name+"=...")
+        }
+    }
+    return strings.Join(parts, " ")
+}
+
+// ParseVars turns k=v words into a map.
+func ParseVars(words []string) (map[string]string, error) {
+    vars := map[string]string{}
+    for _, w := range words {
+        k, v, ok := strings.Cut(w, "=")
+        if !ok || strings.TrimSpace(k) == "" {
+            return nil, fmt.Errorf("expected name=value, got %q", w)
+        }
+        vars[strings.TrimSpace(k)] = v
+    }
+    return vars, nil
+}
@@ -0,0 +1,165 @@
+// Package tokens estimates how many tokens a text takes, offline and
+// without a vocabulary. It splits text the way GPT tokenizers pre-tokenize
+// it (words with their leading space, digit groups, punctuation and
+// whitespace runs) and prices each piece from its length. The estimate is
+// meant to err slightly high for code and English prose.
+package tokens
+
+import (
+    "strings"
+    "unicode"
+    "unicode/utf8"
+)
+
+// Count returns the estimated number of tokens in s.
+func Count(s string) int {
+    n := 0
+    for i := 0; i < len(s); {
+        r, size := utf8.DecodeRuneInString(s[i:])
+        j := i + size
+        switch {
+        case isLetter(r):
+            j = scan(s, j, isLetter)
+            n += word(s[i:j])
+        case unicode.IsDigit(r):
+            j = scan(s, j, unicode.IsDigit)
+            n += (utf8.RuneCountInString(s[i:j]) + 2) / 3
+        case unicode.IsSpace(r):
+            j = scan(s, j, unicode.IsSpace)
+            run := j - i
+            if j < len(s) && !strings.ContainsAny(s[i:j], "\r\n") {
+                // The last space joins the word or symbol after it.
+                run--
+            }
+            if run > 0 {
+                n += 1 + (run-1)/16
+            }
+        default:
+            j = scan(s, j, isSymbol)
+            n += (utf8.RuneCountInString(s[i:j]) + 1) / 2
+        }
+        i = j
+    }
+    return n
+}
+
+// word prices a run of letters. ASCII words are split at camelCase
+// boundaries; a subword is one token, plus one per further seven letters.
+// Other scripts cost a token per letter, CJK about two per character.
+func word(w string) int {
+    n, letters := 0, 0
+    subword := func() {
+        if letters > 0 {
+            n += 1 + (letters-1)/7
+        }
+        letters = 0
+    }
+    prevLower := false
+    for _, r := range w {
+        switch {
+        case r >= utf8.RuneSelf:
+            subword()
+            n++
+            if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+                n++
+            }
+            prevLower = false
+            continue
+        case r >= 'A' && r <= 'Z':
+            if prevLower {
+                subword()
+            }
+            prevLower = false
+        default:
+            prevLower = true
+        }
+        letters++
+    }
+    subword()
+    return n
+}
+
+func isLetter(r rune) bool {
+    return unicode.IsLetter(r) || unicode.Is(unicode.Mn, r)
+}
+
+func isSymbol(r rune) bool {
+    return !isLetter(r) && !unicode.IsDigit(r) && !unicode.IsSpace(r)
+}
+
+func scan(s string, i int, in func(rune) bool) int {
+    for i < len(s) {
+        r, size := utf8.DecodeRuneInString(s[i:])
+        if !in(r) {
+            break
+        }
+        i += size
+    }
+    return i
+}
+
+// Truncate returns the longest prefix of s, cut at a line end where
+// possible, that fits in max tokens, and whether anything was cut.
+func Truncate(s string, max int) (string, bool) {
+    if Count(s) <= max {
+        return s, false
+    }
+    head, _ := cut(s, max)
+    return head, true
+}
+
+// Split breaks s into pieces of at most max tokens each, at line ends
+// where possible.
+func Split(s string, max int) []string {
+    var parts []string
+    for s != "" {
+        head, rest := cut(s, max)
+        parts = append(parts, head)
+        s = rest
+    }
+    return parts
+}
+
+// cut splits s after the whole lines that fit in max tokens. A single line
+// longer than max is cut inside; at least one rune is always taken.
+func cut(s string, max int) (head, rest string) {
+    used, end := 0, 0
+    for end < len(s) {
+        next := strings.IndexByte(s[end:], '\n')
+        if next < 0 {
+            next = len(s)
+        } else {
+            next += end + 1
+        }
+        n := Count(s[end:next])
+        if used+n > max {
+            break
+        }
+        used += n
+        end = next
+    }
+    if end == 0 {
+        // The first line alone is too long: find how much of it fits.
+        line := len(s)
+        if i := strings.IndexByte(s, '\n'); i >= 0 {
+            line = i + 1
+        }
+        lo, hi := 0, line
+        for lo < hi {
+            mid := (lo + hi + 1) / 2
+            if Count(s[:mid]) <= max {
+                lo = mid
+            } else {
+                hi = mid - 1
+            }
+        }
+        end = lo
+        for end > 0 && end < len(s) && !utf8.RuneStart(s[end]) {
+            end--
+        }
+        if end == 0 {
+            _, end = utf8.DecodeRuneInString(s)
+        }
+    }
+    return s[:end], s[end:]
+}
@@ -4,17 +4,15 @@ import (
     "bufio"
     "context"
     "encoding/json"
+    "errors"
     "fmt"
     "io"
-    "log"
     "os"
     "os/user"
-    "path/filepath"
     "strconv"
     "strings"
     "time"
 
-    markdown "github.com/MichaelMure/go-term-markdown"
     "github.com/chromedp/cdproto/runtime"
     "github.com/chromedp/chromedp"
     "github.com/sirupsen/logrus"
@@ -25,13 +23,57 @@ import (
 
 const ctxTime = 2000
 
-type attachment struct{ path string; content string }
+// modelJS reads the model name from ChatGPT's model switcher, if shown.
+const modelJS = `(() => {
+    const b = document.querySelector('[data-testid="model-switcher-dropdown-button"]');
+    return b ? b.innerText.trim() : '';
+})()`
+
+type attachment struct {
+    path    string
+    content string
+    sent    bool // already pasted into the conversation
+    // pinned attachments are re-read before every turn and sent again
+    // when their content changed.
+    pinned bool
+    // reload re-reads the attachment from its source; nil if it cannot be
+    // re-read (e.g. content supplied by an external command).
+    reload func() (string, error)
+}
+
+// Options holds per-invocation settings from command-line flags.
+type Options struct {
+    // ForceUnlock removes a profile lock whose owner is no longer running.
+    ForceUnlock bool
+    // Style overrides the configured response style (see parseStyle).
+    Style string
+    // Template is a prompt template sent as the first prompt, with Vars
+    // filled in; a prompt given as well is appended to it.
+    Template string
+    Vars     map[string]string
+    // NoContext skips the project context file (.chatbang.md).
+    NoContext bool
+}
 
 type App struct {
+    Options Options
+
     defaultBrowser string
+    styleSpec      string
+    historySize    int
+    saveSessions   bool
+    shellAllow     []string       // command prefixes !`cmd` may run unconfirmed
+    attachHeader   string         // introduces attachments in a prompt
+    tokenLimit     int            // estimated tokens one message may hold
+    modelLimits    map[string]int // per-model tokenLimit, by lowercased model name
+    autoSplit      bool           // send oversized attachments in parts unasked
     profileDir     string
     configDir      string
     mcpMgr         *mcp.Manager
+
+    browser        *browser
+    conversationID string
+    model          string // as shown in ChatGPT's model switcher
 }
 
 func New() *App {
@@ -65,7 +107,14 @@ func New() *App {
         configFile.Seek(0, 0)
     }
 
-    var defaultBrowser string
+    var defaultBrowser, styleSpec string
+    var historySize int
+    saveSessions := true
+    var shellAllow []string
+    attachHeader := defaultAttachHeader
+    tokenLimit := defaultTokenLimit
+    modelLimits := map[string]int{}
+    autoSplit := true
     scanner := bufio.NewScanner(configFile)
     for scanner.Scan() {
         line := strings.TrimSpace(scanner.Text())
@@ -78,16 +127,54 @@ func New() *App {
         }
         key := strings.TrimSpace(parts[0])
         value := strings.TrimSpace(parts[1])
-        if key == "browser" {
+        switch key {
+        case "browser":
             defaultBrowser = value
+        case "style":
+            styleSpec = value
+        case "history_size":
+            historySize, _ = strconv.Atoi(value)
+        case "save_sessions":
+            b, err := strconv.ParseBool(value)
+            if err != nil {
+                logrus.WithField("value", value).Warn("invalid save_sessions; keeping transcripts on")
+                break
+            }
+            saveSessions = b
+        case "attachment_header":
+            attachHeader = strings.ReplaceAll(value, `\n`, "\n")
+        case "shell_allow":
+            for _, c := range strings.Split(value, ",") {
+                if c = strings.TrimSpace(c); c != "" {
+                    shellAllow = append(shellAllow, c)
+                }
+            }
+        case "auto_split":
+            b, err := strconv.ParseBool(value)
+            if err != nil {
+                logrus.WithField("value", value).Warn("invalid auto_split; keeping it on")
+                break
+            }
+            autoSplit = b
+        case "token_limit":
+            if n, err := strconv.Atoi(value); err == nil && n > 0 {
+                tokenLimit = n
+            }
+        default:
+            if model, ok := strings.CutPrefix(key, "token_limit."); ok && model != "" {
+                if n, err := strconv.Atoi(value); err == nil && n > 0 {
+                    modelLimits[strings.ToLower(model)] = n
+                }
+            }
         }
     }
 
-    a := &App{defaultBrowser: defaultBrowser, profileDir: profileDir, configDir: configDir}
+    a := &App{defaultBrowser: defaultBrowser, styleSpec: styleSpec, historySize: historySize, saveSessions: saveSessions, shellAllow: shellAllow, attachHeader: attachHeader, tokenLimit: tokenLimit, modelLimits: modelLimits, autoSplit: autoSplit, profileDir: profileDir, configDir: configDir}
     logrus.WithFields(logrus.Fields{
         "configDir":   configDir,
         "profileDir":  profileDir,
         "browser":     defaultBrowser,
+        "style":       styleSpec,
     }).Info("initialized app config")
     a.initMCPProviders()
     return a
@@ -95,156 +182,51 @@ func New() *App {
 
 // Run starts interactive chat (or uses a provided first prompt).
 func (a *App) Run(firstPrompt string) error {
-    attachments := []attachment{}
-
-    allocatorCtx, cancel := chromedp.NewExecAllocator(context.Background(),
-        append(chromedp.DefaultExecAllocatorOptions[:],
-            chromedp.ExecPath(a.defaultBrowser),
-            chromedp.Flag("disable-blink-features", "AutomationControlled"),
-            chromedp.Flag("exclude-switches", "enable-automation"),
-            chromedp.Flag("disable-extensions", false),
-            chromedp.UserAgent("Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"),
-            chromedp.Flag("disable-default-apps", false),
-            chromedp.Flag("disable-dev-shm-usage", false),
-            chromedp.Flag("disable-gpu", false),
-            chromedp.Flag("headless", false),
-            chromedp.UserDataDir(a.profileDir),
-            chromedp.Flag("profile-directory", "Default"),
-        )...,
-    )
-    defer cancel()
-
-    ctx, cancel := chromedp.NewContext(allocatorCtx)
-    defer cancel()
-
-    taskCtx, taskCancel := context.WithTimeout(ctx, ctxTime*time.Second)
-    defer taskCancel()
-
-    logrus.WithFields(logrus.Fields{"browser": a.defaultBrowser}).Info("starting chat session and navigating to chatgpt.com")
-    if err := chromedp.Run(taskCtx, chromedp.Navigate(`https://chatgpt.com`)); err != nil {
+    spec := a.styleSpec
+    if a.Options.Style != "" {
+        spec = a.Options.Style
+    }
+    style, err := parseStyle(spec)
+    if err != nil {
         return err
     }
 
-    // Scanner loop
-    fmt.Print("> ")
-    promptScanner := bufio.NewScanner(os.Stdin)
-
-    // If a first prompt is supplied via args, handle it once then exit
-    if strings.TrimSpace(firstPrompt) != "" {
-        line := strings.TrimSpace(firstPrompt)
-        var b strings.Builder
-        if len(attachments) > 0 {
-            b.WriteString("You have access to the following context files. Use them when answering.\n\n")
-            for _, at := range attachments {
-                b.WriteString("File: ")
-                b.WriteString(at.path)
-                b.WriteString("\n````\n")
-                b.WriteString(at.content)
-                b.WriteString("\n````\n\n")
-            }
+    // Load the project context and render the template before launching
+    // the browser so mistakes in them fail fast.
+    sess := a.newSession(os.Stdin)
+    sess.style = style
+    if !a.Options.NoContext {
+        if _, err := sess.loadContext(); err != nil {
+            fmt.Printf("Warning: project context: %v\n", err)
         }
-        b.WriteString(line)
-        a.runChatGPT(taskCtx, b.String())
-        return nil
     }
-
-    for promptScanner.Scan() {
-        line := strings.TrimSpace(promptScanner.Text())
-        if line == "" {
-            fmt.Print("> ")
-            continue
-        }
-        if strings.HasPrefix(line, ":") {
-            if a.handleLocalCommand(line, &attachments) {
-                fmt.Print("> ")
-                continue
-            }
-            fmt.Println("Unknown command. Try :help")
-            fmt.Print("> ")
-            continue
+    if a.Options.Template != "" {
+        prompt, err := sess.useTemplate(a.Options.Template, a.Options.Vars)
+        if err != nil {
+            return err
         }
-        var b strings.Builder
-        if len(attachments) > 0 {
-            b.WriteString("You have access to the following context files. Use them when answering.\n\n")
-            for _, at := range attachments {
-                b.WriteString("File: ")
-                b.WriteString(at.path)
-                b.WriteString("\n````\n")
-                b.WriteString(at.content)
-                b.WriteString("\n````\n\n")
-            }
+        if strings.TrimSpace(firstPrompt) != "" {
+            prompt += "\n\n" + firstPrompt
         }
-        b.WriteString(line)
-        a.runChatGPT(taskCtx, b.String())
-        return nil
+        firstPrompt = prompt
     }
-    return nil
-}
-
-// Login opens ChatGPT and prompts for clipboard permission within the profile.
-func (a *App) Login() error {
-    allocatorCtx, cancel := chromedp.NewExecAllocator(context.Background(),
-        append(chromedp.DefaultExecAllocatorOptions[:],
-            chromedp.ExecPath(a.defaultBrowser),
-            chromedp.Flag("disable-blink-features", "AutomationControlled"),
-            chromedp.Flag("exclude-switches", "enable-automation"),
-            chromedp.Flag("disable-extensions", false),
-            chromedp.UserAgent("Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"),
-            chromedp.Flag("disable-default-apps", false),
-            chromedp.Flag("disable-dev-shm-usage", false),
-            chromedp.Flag("disable-gpu", false),
-            chromedp.Flag("headless", false),
-            chromedp.UserDataDir(a.profileDir),
-            chromedp.Flag("profile-directory", "Default"),
-        )...,
-    )
-    defer cancel()
-
-    ctx, cancel := chromedp.NewContext(allocatorCtx)
-    defer cancel()
 
-    taskCtx, taskCancel := context.WithTimeout(ctx, ctxTime*time.Second)
-    defer taskCancel()
+    remoteURL, err := a.acquireProfile()
+    if err != nil {
+        return err
+    }
+    a.browser = newBrowser(a.allocatorOptions(), a.profileDir, remoteURL)
+    if err := a.browser.launch(); err != nil {
+        return err
+    }
+    defer a.browser.close()
 
-    logrus.Info("opening login/profile setup flow")
-    if err := chromedp.Run(taskCtx,
-        chromedp.Navigate(`https://www.chatgpt.com/`),
-        chromedp.Evaluate(`(async () => {
-            const permName = 'clipboard-read';
-            try {
-                const p = await navigator.permissions.query({ name: permName });
-                if (p.state !== 'granted') {
-                    alert("Please allow clipboard access in the popup that will appear now.");
-                }
-            } catch (e) {
-                try { await navigator.clipboard.readText(); } catch (_) {
-                    alert("Please allow clipboard access in the popup that will appear now.");
-                }
-            }
-        })();`, nil),
-        chromedp.Evaluate(`navigator.clipboard.readText().catch(() => {});`, nil),
-    ); err != nil {
+    logrus.WithFields(logrus.Fields{"browser": a.defaultBrowser}).Info("starting chat session and navigating to chatgpt.com")
+    if err := chromedp.Run(a.browser.Ctx(), chromedp.Navigate(chatURL)); err != nil {
         return err
     }
-    done := make(chan bool)
-    go func() {
-        ticker := time.NewTicker(ctxTime * time.Second)
-        defer ticker.Stop()
-        for {
-            select {
-            case <-ticker.C:
-                if err := chromedp.Run(ctx, chromedp.Evaluate(`document.readyState`, nil)); err != nil {
-                    done <- true
-                    return
-                }
-            case <-ctx.Done():
-                done <- true
-                return
-            }
-        }
-    }()
-    <-done
-    return nil
+
+    return sess.run(firstPrompt)
 }
 
 // init MCP
@@ -264,132 +246,6 @@ func (a *App) initMCPProviders() {
     a.mcpMgr = mgr
 }
 
-// Local ":" commands
-func (a *App) handleLocalCommand(line string, attachments *[]attachment) bool {
-    fields := strings.Fields(line)
-    if len(fields) == 0 {
-        return true
-    }
-    cmd := strings.TrimPrefix(strings.ToLower(fields[0]), ":")
-    switch cmd {
-    case "help":
-        fmt.Println("Commands:\n  :attach <path> [limit=N]\n  :list [path] [depth=N]\n  :search <root> <query> [globs=pat1,pat2]\n  :stat <path>\n  :clear (clear attachments)")
-        return true
-    case "clear":
-        *attachments = (*attachments)[:0]
-        fmt.Println("Attachments cleared.")
-        return true
-    case "attach":
-        if len(fields) < 2 {
-            fmt.Println("Usage: :attach <path> [limit=N]")
-            return true
-        }
-        path := fields[1]
-        limit := 0
-        if len(fields) >= 3 && strings.HasPrefix(fields[2], "limit=") {
-            limit, _ = strconv.Atoi(strings.TrimPrefix(fields[2], "limit="))
-        }
-        contents, truncated, err := a.providerRead(path, 0, limit)
-        if err != nil {
-            fmt.Printf("attach error: %v\n", err)
-            return true
-        }
-        if truncated {
-            fmt.Println("Note: content truncated.")
-        }
-        *attachments = append(*attachments, attachment{path: path, content: contents})
-        fmt.Printf("Attached %s (%d chars).\n", path, len(contents))
-        logrus.WithFields(logrus.Fields{"path": path, "chars": len(contents), "truncated": truncated}).Info(":attach")
-        return true
-    case "list":
-        path := "."
-        depth := 1
-        if len(fields) >= 2 {
-            path = fields[1]
-        }
-        if len(fields) >= 3 && strings.HasPrefix(fields[2], "depth=") {
-            depth, _ = strconv.Atoi(strings.TrimPrefix(fields[2], "depth="))
-        }
-        entries, err := a.providerList(path, depth)
-        if err != nil {
-            fmt.Printf("list error: %v\n", err)
-            return true
-        }
-        logrus.WithFields(logrus.Fields{"path": path, "depth": depth, "count": len(entries)}).Info(":list")
-        for _, e := range entries {
-            name, _ := e["name"].(string)
-            dir, _ := e["dir"].(bool)
-            p, _ := e["path"].(string)
-            if rel, err := filepath.Rel(".", p); err == nil {
-                p = rel
-            }
-            if dir {
-                fmt.Printf("[D] %s\t%s\n", name, p)
-            } else {
-                fmt.Printf("[F] %s\t%s\n", name, p)
-            }
-        }
-        return true
-    case "search":
-        if len(fields) < 3 {
-            fmt.Println("Usage: :search <root> <query> [globs=pat1,pat2]")
-            return true
-        }
-        root := fields[1]
-        query := strings.Join(fields[2:], " ")
-        globs := []string{}
-        if i := strings.Index(query, "globs="); i >= 0 {
-            parts := strings.Fields(query)
-            nq := []string{}
-            for _, tok := range parts {
-                if strings.HasPrefix(tok, "globs=") {
-                    rest := strings.TrimPrefix(tok, "globs=")
-                    for _, p := range strings.Split(rest, ",") {
-                        p = strings.TrimSpace(p)
-                        if p != "" {
-                            globs = append(globs, p)
-                        }
-                    }
-                } else {
-                    nq = append(nq, tok)
-                }
-            }
-            query = strings.Join(nq, " ")
-        }
-        matches, err := a.providerSearch(root, query, globs)
-        if err != nil {
-            fmt.Printf("search error: %v\n", err)
-            return true
-        }
-        logrus.WithFields(logrus.Fields{"root": root, "query": query, "globs": globs, "count": len(matches)}).Info(":search")
-        for _, m := range matches {
-            p, _ := m["path"].(string)
-            mt, _ := m["mimeType"].(string)
-            if rel, err := filepath.Rel(".", p); err == nil {
-                p = rel
-            }
-            fmt.Printf("- %s (%s)\n", p, mt)
-        }
-        return true
-    case "stat":
-        if len(fields) < 2 {
-            fmt.Println("Usage: :stat <path>")
-            return true
-        }
-        info, err := a.providerStat(fields[1])
-        if err != nil {
-            fmt.Printf("stat error: %v\n", err)
-            return true
-        }
-        logrus.WithFields(logrus.Fields{"path": fields[1]}).Info(":stat")
-        b, _ := json.MarshalIndent(info, "", "  ")
-        fmt.Println(string(b))
-        return true
-    default:
-        return false
-    }
-}
-
 // Provider helpers
 func (a *App) getDefaultProvider() mcp.Provider {
     if a.mcpMgr == nil {
@@ -417,8 +273,8 @@ func (a *App) providerRead(path string, offset, limit int) (contents string, tru
     raw, _ := json.Marshal(req)
     res, mErr := p.Handle("tools/call", raw)
     if mErr != nil {
-        logrus.WithFields(logrus.Fields{"tool": "fs.read", "path": path, "offset": offset, "limit": limit, "elapsed": time.Since(start)}).WithError(fmt.Errorf(mErr.Message)).Error("mcp call failed")
-        return "", false, fmt.Errorf(mErr.Message)
+        logrus.WithFields(logrus.Fields{"tool": "fs.read", "path": path, "offset": offset, "limit": limit, "elapsed": time.Since(start)}).WithError(errors.New(mErr.Message)).Error("mcp call failed")
+        return "", false, errors.New(mErr.Message)
     }
     m, ok := res.(map[string]any)
     if !ok {
@@ -441,19 +297,13 @@ func (a *App) providerList(path string, depth int) ([]map[string]any, error) {
     raw, _ := json.Marshal(req)
     res, mErr := p.Handle("tools/call", raw)
     if mErr != nil {
-        logrus.WithFields(logrus.Fields{"tool": "fs.list", "path": path, "depth": depth, "elapsed": time.Since(start)}).WithError(fmt.Errorf(mErr.Message)).Error("mcp call failed")
-        return nil, fmt.Errorf(mErr.Message)
+        logrus.WithFields(logrus.Fields{"tool": "fs.list", "path": path, "depth": depth, "elapsed": time.Since(start)}).WithError(errors.New(mErr.Message)).Error("mcp call failed")
+        return nil, errors.New(mErr.Message)
     }
-    arr, ok := res.([]any)
+    out, ok := resultList(res, "entries")
     if !ok {
         return nil, fmt.Errorf("unexpected response type")
     }
-    out := make([]map[string]any, 0, len(arr))
-    for _, it := range arr {
-        if m, ok := it.(map[string]any); ok {
-            out = append(out, m)
-        }
-    }
     logrus.WithFields(logrus.Fields{"tool": "fs.list", "path": path, "depth": depth, "count": len(out), "elapsed": time.Since(start)}).Info("mcp call")
     return out, nil
 }
@@ -472,23 +322,63 @@ func (a *App) providerSearch(root, query string, globs []string) ([]map[string]a
     raw, _ := json.Marshal(req)
     res, mErr := p.Handle("tools/call", raw)
     if mErr != nil {
-        logrus.WithFields(logrus.Fields{"tool": "fs.search", "root": root, "query": query, "globs": globs, "elapsed": time.Since(start)}).WithError(fmt.Errorf(mErr.Message)).Error("mcp call failed")
-        return nil, fmt.Errorf(mErr.Message)
+        logrus.WithFields(logrus.Fields{"tool": "fs.search", "root": root, "query": query, "globs": globs, "elapsed": time.Since(start)}).WithError(errors.New(mErr.Message)).Error("mcp call failed")
+        return nil, errors.New(mErr.Message)
     }
-    arr, ok := res.([]any)
+    out, ok := resultList(res, "matches")
     if !ok {
         return nil, fmt.Errorf("unexpected response type")
     }
-    out := make([]map[string]any, 0, len(arr))
-    for _, it := range arr {
-        if m, ok := it.(map[string]any); ok {
-            out = append(out, m)
-        }
-    }
     logrus.WithFields(logrus.Fields{"tool": "fs.search", "root": root, "query": query, "globs": globs, "count": len(out), "elapsed": time.Since(start)}).Info("mcp call")
     return out, nil
 }
 
+// providerWrite writes a file through the provider. With dryRun it only
+// checks that the write would be allowed (roots and allow_write).
+func (a *App) providerWrite(path, contents string, mkdir, dryRun bool) (created bool, err error) {
+    p := a.getDefaultProvider()
+    if p == nil {
+        return false, fmt.Errorf("no MCP providers configured")
+    }
+    start := time.Now()
+    args := map[string]any{"path": path, "contents": contents, "mkdir": mkdir, "dry_run": dryRun}
+    req := map[string]any{"name": "fs.write", "arguments": args}
+    raw, _ := json.Marshal(req)
+    res, mErr := p.Handle("tools/call", raw)
+    if mErr != nil {
+        logrus.WithFields(logrus.Fields{"tool": "fs.write", "path": path, "elapsed": time.Since(start)}).WithError(errors.New(mErr.Message)).Error("mcp call failed")
+        return false, errors.New(mErr.Message)
+    }
+    m, ok := res.(map[string]any)
+    if !ok {
+        return false, fmt.Errorf("unexpected response type")
+    }
+    created, _ = m["created"].(bool)
+    logrus.WithFields(logrus.Fields{"tool": "fs.write", "path": path, "bytes": len(contents), "created": created, "dry_run": dryRun, "elapsed": time.Since(start)}).Info("mcp call")
+    return created, nil
+}
+
+// resultList extracts a list of objects from a tool result, either bare or
+// wrapped in an object under key (as the fs provider returns them).
+func resultList(res any, key string) ([]map[string]any, bool) {
+    if m, ok := res.(map[string]any); ok {
+        res = m[key]
+    }
+    switch v := res.(type) {
+    case []map[string]any:
+        return v, true
+    case []any:
+        out := make([]map[string]any, 0, len(v))
+        for _, it := range v {
+            if m, ok := it.(map[string]any); ok {
+                out = append(out, m)
+            }
+        }
+        return out, true
+    }
+    return nil, false
+}
+
 func (a *App) providerStat(path string) (map[string]any, error) {
     p := a.getDefaultProvider()
     if p == nil {
@@ -500,8 +390,8 @@ func (a *App) providerStat(path string) (map[string]any, error) {
     raw, _ := json.Marshal(req)
     res, mErr := p.Handle("tools/call", raw)
     if mErr != nil {
-        logrus.WithFields(logrus.Fields{"tool": "fs.stat", "path": path, "elapsed": time.Since(start)}).WithError(fmt.Errorf(mErr.Message)).Error("mcp call failed")
-        return nil, fmt.Errorf(mErr.Message)
+        logrus.WithFields(logrus.Fields{"tool": "fs.stat", "path": path, "elapsed": time.Since(start)}).WithError(errors.New(mErr.Message)).Error("mcp call failed")
+        return nil, errors.New(mErr.Message)
     }
     m, ok := res.(map[string]any)
     if !ok {
@@ -511,8 +401,33 @@ func (a *App) providerStat(path string) (map[string]any, error) {
     return m, nil
 }
 
-// ChatGPT interaction logic (copied from previous main.go)
-func (a *App) runChatGPT(taskCtx context.Context, modifiedPrompt string) {
+// ask sends prompt and returns the answer. If the browser crashes or its
+// window is closed mid-turn, it relaunches Chrome, reopens the current
+// conversation and re-sends the pending prompt.
+func (a *App) ask(prompt string) (string, error) {
+    for attempt := 0; ; attempt++ {
+        answer, err := a.sendPrompt(a.browser.Ctx(), prompt)
+        if err == nil {
+            return answer, nil
+        }
+        if !a.browser.isBrowserGone(err) || attempt >= maxRelaunches {
+            logrus.WithError(err).Error("failed to get response")
+            return "", err
+        }
+        fmt.Println("[Browser lost; relaunching and restoring the conversation...]")
+        url := conversationURL(a.conversationID)
+        logrus.WithFields(logrus.Fields{"attempt": attempt + 1, "url": url}).Warn("relaunching browser")
+        if rerr := a.browser.relaunch(url); rerr != nil {
+            logrus.WithError(rerr).Error("failed to relaunch browser")
+            return "", fmt.Errorf("relaunch browser: %w", rerr)
+        }
+        fmt.Printf("[Re-sending your last prompt...]\n\n")
+    }
+}
+
+// sendPrompt types prompt into the composer, waits for the new answer and
+// reads it back through its copy button and the clipboard.
+func (a *App) sendPrompt(taskCtx context.Context, modifiedPrompt string) (string, error) {
     js := `new Promise((resolve, reject) => {
         navigator.permissions.query({ name: 'clipboard-read' }).then(permissionStatus => {
             if (permissionStatus.state === 'granted' || permissionStatus.state === 'prompt') {
@@ -529,16 +444,19 @@ func (a *App) runChatGPT(taskCtx context.Context, modifiedPrompt string) {
         });
     });`
 
-    var copiedText string
-    var result []byte
+    // Generic copy button selector for finished answers
+    buttonDiv := `.markdown.prose.w-full:not(.result-streaming) [data-testid*="button-to-copy"]`
+    countJS := fmt.Sprintf(`document.querySelectorAll('%s').length`, buttonDiv)
 
     // Log prompt send (avoid logging full content at info level)
     preview := modifiedPrompt
     if len(preview) > 120 { preview = preview[:120] + "..." }
     logrus.WithFields(logrus.Fields{"chars": len(modifiedPrompt), "preview": preview}).Info("sending prompt to ChatGPT")
 
+    var before int
     err := chromedp.Run(taskCtx,
         chromedp.WaitVisible(`#prompt-textarea`, chromedp.ByID),
+        chromedp.Evaluate(countJS, &before),
         chromedp.Click(`#prompt-textarea`, chromedp.ByID),
         chromedp.SendKeys(`#prompt-textarea`, modifiedPrompt, chromedp.ByID),
         chromedp.Click(`#composer-submit-button`, chromedp.ByID),
@@ -546,19 +464,26 @@ func (a *App) runChatGPT(taskCtx context.Context, modifiedPrompt string) {
     )
     if err != nil {
         logrus.WithError(err).Error("failed to send prompt")
-        log.Fatal(err)
+        return "", err
     }
 
-    // Scrape answer with copy button polling
-    buttonDiv := `.markdown.prose.w-full:not(.result-streaming) [data-testid*="button-to-copy"]` // Generic copy button selector
-    fmt.Printf("[Thinking...]\n\n")
-
-    for {
-        if copiedText != modifiedPrompt && len(copiedText) > 0 {
-            break
+    // Scrape answer with copy button polling: wait for a new finished answer,
+    // click its copy button, then read the clipboard.
+    var copiedText string
+    for copiedText == "" || copiedText == modifiedPrompt {
+        var count int
+        err = chromedp.Run(taskCtx,
+            chromedp.Sleep(1*time.Second),
+            chromedp.Evaluate(countJS, &count),
+        )
+        if err != nil {
+            logrus.WithError(err).Error("failed while fetching response")
+            return "", err
+        }
+        if count <= before {
+            continue
         }
         err = chromedp.Run(taskCtx,
-            chromedp.WaitVisible(buttonDiv, chromedp.ByQuery),
             chromedp.Evaluate(fmt.Sprintf(`
                 (() => {
                     let buttons = document.querySelectorAll('%s');
@@ -569,65 +494,24 @@ func (a *App) runChatGPT(taskCtx context.Context, modifiedPrompt string) {
                 `, buttonDiv), nil),
             chromedp.Evaluate(js, &copiedText, func(p *runtime.EvaluateParams) *runtime.EvaluateParams { return p.WithAwaitPromise(true) }),
         )
-        result = markdown.Render(string(copiedText), 80, 2)
-    }
-    if err != nil {
-        logrus.WithError(err).Error("failed while fetching response")
-        log.Fatal(err)
-    }
-    logrus.WithField("chars", len(copiedText)).Info("received response from ChatGPT")
-    fmt.Println(string(result))
-    fmt.Print("> ")
-
-    // Follow-up loop
-    promptScanner := bufio.NewScanner(os.Stdin)
-    for promptScanner.Scan() {
-        prompt := promptScanner.Text()
-        modifiedPrompt = prompt + " (Make an answer in less than 5 lines)."
-        if len(prompt) == 0 {
-            fmt.Print("> ")
-            continue
-        }
-        fmt.Printf("[Thinking...]\n\n")
-        // Log next prompt
-        preview = modifiedPrompt
-        if len(preview) > 120 { preview = preview[:120] + "..." }
-        logrus.WithFields(logrus.Fields{"chars": len(modifiedPrompt), "preview": preview}).Info("sending follow-up prompt to ChatGPT")
-
-        err := chromedp.Run(taskCtx,
-            chromedp.WaitVisible(`#prompt-textarea`, chromedp.ByID),
-            chromedp.Click(`#prompt-textarea`, chromedp.ByID),
-            chromedp.SendKeys(`#prompt-textarea`, modifiedPrompt, chromedp.ByID),
-            chromedp.Click(`#composer-submit-button`, chromedp.ByID),
-            chromedp.Click(`#prompt-textarea`, chromedp.ByID),
-        )
         if err != nil {
-            logrus.WithError(err).Error("failed to send follow-up prompt")
-            log.Fatal(err)
+            logrus.WithError(err).Error("failed while fetching response")
+            return "", err
         }
-        result = markdown.Render(string(copiedText), 80, 2)
-        copiedText = ""
-        for {
-            if copiedText != modifiedPrompt && len(copiedText) > 0 {
-                break
-            }
-            err = chromedp.Run(taskCtx,
-                chromedp.Sleep(3*time.Second),
-                chromedp.Evaluate(fmt.Sprintf(`
-                    (() => {
-                        let buttons = document.querySelectorAll('%s');
-                        if (buttons.length > 0) {
-                            buttons[buttons.length - 1].click();
-                        }
-                    })()
-                    `, buttonDiv), nil),
-                chromedp.Sleep(1*time.Second),
-                chromedp.Evaluate(js, &copiedText, func(p *runtime.EvaluateParams) *runtime.EvaluateParams { return p.WithAwaitPromise(true) }),
-            )
-            result = markdown.Render(string(copiedText), 80, 2)
+    }
+
+    // Remember the conversation so it can be restored after a crash, and
+    // the model for the prompt line.
+    var loc, model string
+    if err := chromedp.Run(taskCtx,
+        chromedp.Location(&loc),
+        chromedp.Evaluate(modelJS, &model),
+    ); err == nil {
+        if id := conversationIDFromURL(loc); id != "" {
+            a.conversationID = id
         }
-        logrus.WithField("chars", len(copiedText)).Info("received response from ChatGPT")
-        fmt.Println(string(result))
-        fmt.Print("> ")
+        a.model = strings.Join(strings.Fields(model), " ")
     }
+    logrus.WithFields(logrus.Fields{"chars": len(copiedText), "conversation": a.conversationID}).Info("received response from ChatGPT")
+    return copiedText, nil
 }
@@ -0,0 +1,269 @@
+package app
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+
+    "github.com/sirupsen/logrus"
+
+    "gg/internal/codeblock"
+    "gg/internal/diff"
+    "gg/internal/patch"
+)
+
+// appliedFile records one file changed by :apply, for :undo-apply.
+type appliedFile struct {
+    path    string
+    before  string
+    after   string
+    existed bool
+}
+
+// defaultFuzz is how many context lines :apply may ignore at each hunk end.
+const defaultFuzz = 2
+
+func init() {
+    RegisterCommand(Command{
+        Name: "apply",
+        Help: "apply unified diffs from the last answer, hunk by hunk",
+        Long: "Find unified diffs in the last answer (or use code block N) and apply them to the working tree. Each hunk is shown with where it applies and can be accepted (y), rejected (n), accepted with all remaining hunks (a), or the whole apply cancelled (q). Hunks are matched with offset and fuzz tolerance. Files must be inside the MCP roots with allow_write enabled; originals are kept as <file>.orig and :undo-apply reverts the last apply.",
+        Args: []ArgSpec{{Name: "N", Optional: true}},
+        Options: []OptionSpec{
+            {Name: "fuzz", Value: "N", Help: "context lines that may mismatch at each hunk end (default 2)"},
+            {Name: "path", Value: "file", Help: "target file for a diff without file names"},
+        },
+        Run: func(c *Context) error {
+            fuzz, err := c.Args.Int("fuzz", defaultFuzz)
+            if err != nil {
+                return err
+            }
+            files, err := c.s.patchFiles(c.Args.Arg(0))
+            if err != nil {
+                return err
+            }
+            if p, ok := c.Args.Option("path"); ok {
+                if len(files) != 1 {
+                    return fmt.Errorf("path= needs a diff of exactly one file (found %d)", len(files))
+                }
+                files[0].Old, files[0].New = p, p
+            }
+            return c.s.applyPatch(files, fuzz)
+        },
+    })
+    RegisterCommand(Command{
+        Name: "undo-apply",
+        Help: "revert the files changed by the last :apply",
+        Run: func(c *Context) error {
+            return c.s.undoApply()
+        },
+    })
+}
+
+// patchFiles parses the diffs of block n, or of every diff block in the last
+// answer (falling back to the answer itself if it is a bare diff).
+func (s *session) patchFiles(n string) ([]patch.File, error) {
+    var texts []string
+    if n != "" {
+        b, err := s.block(n)
+        if err != nil {
+            return nil, err
+        }
+        texts = append(texts, b.Code)
+    } else {
+        for _, b := range codeblock.Extract(s.lastAnswer) {
+            if b.Lang == "diff" || b.Lang == "patch" || patch.LooksLikePatch(b.Code) {
+                texts = append(texts, b.Code)
+            }
+        }
+        if len(texts) == 0 && patch.LooksLikePatch(s.lastAnswer) {
+            texts = append(texts, s.lastAnswer)
+        }
+    }
+    if len(texts) == 0 {
+        return nil, fmt.Errorf("no diff found in the last answer")
+    }
+    var files []patch.File
+    for _, t := range texts {
+        parsed, err := patch.Parse(t)
+        if err != nil {
+            return nil, err
+        }
+        files = append(files, parsed...)
+    }
+    return files, nil
+}
+
+// applyPatch walks the hunks of files interactively and writes the accepted
+// changes.
+func (s *session) applyPatch(files []patch.File, fuzz int) error {
+    color := stdoutIsTerminal()
+    all := false
+    var plans []appliedFile
+    accepted, rejected, failed := 0, 0, 0
+    for _, f := range files {
+        name := f.Path()
+        if name == "" || name == patch.DevNull {
+            fmt.Println("Skipping a diff without a file name (use path=<file>).")
+            continue
+        }
+        if f.New == patch.DevNull {
+            fmt.Printf("Skipping deletion of %s (not supported).\n", name)
+            continue
+        }
+        path, err := filepath.Abs(name)
+        if err != nil {
+            return err
+        }
+        if _, err := s.app.providerWrite(path, "", true, true); err != nil {
+            fmt.Printf("Skipping %s: %v\n", name, err)
+            continue
+        }
+        before, existed, err := s.app.readForWrite(path)
+        if err != nil {
+            fmt.Printf("Skipping %s: %v\n", name, err)
+            continue
+        }
+        if !existed && f.Old != patch.DevNull {
+            fmt.Printf("Skipping %s: file does not exist.\n", name)
+            continue
+        }
+
+        lines, eol := patch.SplitLines(before)
+        delta := 0
+        changed := false
+        for i, h := range f.Hunks {
+            label := fmt.Sprintf("%s hunk %d/%d", name, i+1, len(f.Hunks))
+            m, ok := h.Locate(lines, delta, fuzz)
+            if !ok {
+                fmt.Printf("%s: FAILED, context not found\n", label)
+                failed++
+                continue
+            }
+            delta += m.Offset
+            fmt.Printf("%s: at line %d%s\n", label, m.Line+1, matchNote(m))
+            text := h.String()
+            if color {
+                text = diff.Colorize(text)
+            }
+            fmt.Print(text)
+            if !all {
+                switch s.choose("Apply this hunk? [y,n,a,q]") {
+                case 'y':
+                case 'a':
+                    all = true
+                case 'q':
+                    fmt.Println("Apply cancelled; nothing written.")
+                    return nil
+                default:
+                    rejected++
+                    continue
+                }
+            }
+            lines = patch.Apply(lines, m)
+            delta += m.Delta()
+            changed = true
+            accepted++
+        }
+        if changed {
+            plans = append(plans, appliedFile{path: path, before: before, after: patch.JoinLines(lines, eol), existed: existed})
+        }
+    }
+
+    var done []appliedFile
+    for _, p := range plans {
+        if p.existed {
+            if _, err := s.app.providerWrite(p.path+".orig", p.before, false, false); err != nil {
+                fmt.Printf("%s: backup failed, not patched: %v\n", p.path, err)
+                continue
+            }
+        }
+        if _, err := s.app.providerWrite(p.path, p.after, true, false); err != nil {
+            fmt.Printf("%s: %v\n", p.path, err)
+            continue
+        }
+        added, removed := diff.Stat(diff.Unified(p.path, p.path, p.before, p.after, 0))
+        fmt.Printf("Patched %s (+%d -%d)", p.path, added, removed)
+        if p.existed {
+            fmt.Printf(", backup %s.orig", p.path)
+        }
+        fmt.Println()
+        done = append(done, p)
+    }
+    if len(done) > 0 {
+        s.applied = append(s.applied, done)
+    }
+    fmt.Printf("%d hunks applied, %d rejected, %d failed.\n", accepted, rejected, failed)
+    logrus.WithFields(logrus.Fields{"files": len(done), "accepted": accepted, "rejected": rejected, "failed": failed}).Info(":apply")
+    return nil
+}
+
+func matchNote(m patch.Match) string {
+    var notes []string
+    if m.Offset != 0 {
+        notes = append(notes, fmt.Sprintf("offset %+d", m.Offset))
+    }
+    if m.Fuzz > 0 {
+        notes = append(notes, fmt.Sprintf("fuzz %d", m.Fuzz))
+    }
+    if m.Loose {
+        notes = append(notes, "ignoring whitespace")
+    }
+    if len(notes) == 0 {
+        return ""
+    }
+    return " (" + strings.Join(notes, ", ") + ")"
+}
+
+// undoApply restores the files of the most recent :apply.
+func (s *session) undoApply() error {
+    if len(s.applied) == 0 {
+        return fmt.Errorf("nothing to undo")
+    }
+    top := len(s.applied) - 1
+    last := s.applied[top]
+    for i, f := range last {
+        if err := s.undoFile(f); err != nil {
+            // Keep only the files not restored yet, so a retry does not
+            // ask again about the ones already done.
+            s.applied[top] = last[i:]
+            return err
+        }
+    }
+    s.applied = s.applied[:top]
+    return nil
+}
+
+// undoFile restores one file of an :apply, asking first if it changed
+// since.
+func (s *session) undoFile(f appliedFile) error {
+    current, exists, err := s.app.readForWrite(f.path)
+    if err != nil {
+        return err
+    }
+    if !exists || current != f.after {
+        if !s.confirm(fmt.Sprintf("%s changed since :apply; restore anyway?", f.path)) {
+            fmt.Printf("Left %s as is.\n", f.path)
+            return nil
+        }
+    }
+    if !f.existed {
+        if _, err := s.app.providerWrite(f.path, "", false, true); err != nil {
+            return err
+        }
+        if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+            return err
+        }
+        fmt.Printf("Removed %s.\n", f.path)
+        return nil
+    }
+    if _, err := s.app.providerWrite(f.path, f.before, true, false); err != nil {
+        return err
+    }
+    if orig, _, err := s.app.readForWrite(f.path + ".orig"); err == nil && orig == f.before {
+        os.Remove(f.path + ".orig")
+    }
+    fmt.Printf("Restored %s.\n", f.path)
+    return nil
+}
@@ -0,0 +1,261 @@
+package app
+
+import (
+    "errors"
+    "fmt"
+    "os/exec"
+    "path/filepath"
+    "sort"
+    "strings"
+
+    "github.com/sirupsen/logrus"
+
+    "gg/internal/glob"
+    "gg/internal/tokens"
+)
+
+const (
+    // defaultAttachBudget caps the total size of a multi-file :attach.
+    defaultAttachBudget = 256 * 1024
+    // confirmAttachBytes is the total above which :attach asks first.
+    confirmAttachBytes = 64 * 1024
+    // maxGlobDepth bounds the directory walk for ** patterns.
+    maxGlobDepth = 64
+)
+
+// attachPaths attaches what words name, as for :attach: a single file (or
+// part of one), or the files matching paths, directories and globs, less
+// those matching words starting with "!".
+func (s *session) attachPaths(words []string, limit, budget int) error {
+    var include, exclude []string
+    for _, w := range words {
+        if p, ok := strings.CutPrefix(w, "!"); ok {
+            exclude = append(exclude, p)
+        } else {
+            include = append(include, w)
+        }
+    }
+    if len(include) == 0 {
+        return ErrUsage
+    }
+    if len(include) == 1 && len(exclude) == 0 && !glob.HasMeta(include[0]) && !s.app.isDir(include[0]) {
+        return s.attachFile(include[0], limit)
+    }
+    return s.attachFiles(include, exclude, limit, budget)
+}
+
+// attachFile attaches a single file, or the line range or symbol named by
+// a #L10-20 or ::Name suffix.
+func (s *session) attachFile(spec string, limit int) error {
+    label, contents, truncated, err := s.app.readScoped(spec, limit)
+    if err != nil {
+        return err
+    }
+    if truncated {
+        fmt.Println("Note: content truncated.")
+    }
+    s.attach(label, contents, func() (string, error) {
+        _, contents, _, err := s.app.readScoped(spec, limit)
+        return contents, err
+    })
+    fmt.Printf("Attached %s (%d chars).\n", label, len(contents))
+    logrus.WithFields(logrus.Fields{"path": label, "chars": len(contents), "truncated": truncated}).Info(":attach")
+    return nil
+}
+
+// isDir reports whether path is a directory the provider can see.
+func (a *App) isDir(path string) bool {
+    info, err := a.providerStat(path)
+    if err != nil {
+        return false
+    }
+    dir, _ := info["dir"].(bool)
+    return dir
+}
+
+// globMatches lists the files matching pattern through the provider, with
+// their sizes. A plain file path matches itself; a directory matches the
+// files below it.
+func (a *App) globMatches(pattern string) (map[string]int64, error) {
+    out := map[string]int64{}
+    pattern = filepath.ToSlash(pattern)
+    if !glob.HasMeta(pattern) {
+        info, err := a.providerStat(pattern)
+        if err != nil {
+            return nil, err
+        }
+        if dir, _ := info["dir"].(bool); !dir {
+            size, _ := info["size"].(int64)
+            out[filepath.Clean(pattern)] = size
+            return out, nil
+        }
+        pattern = strings.TrimSuffix(pattern, "/") + "/**"
+    }
+    depth := glob.Depth(pattern)
+    if depth < 0 {
+        depth = maxGlobDepth
+    }
+    entries, err := a.providerList(glob.Base(pattern), depth)
+    if err != nil {
+        return nil, err
+    }
+    for _, e := range entries {
+        p, _ := e["path"].(string)
+        if dir, _ := e["dir"].(bool); dir || !glob.Match(pattern, filepath.ToSlash(p)) {
+            continue
+        }
+        size, _ := e["size"].(int64)
+        out[filepath.Clean(p)] = size
+    }
+    return out, nil
+}
+
+// attachFiles attaches the files matching include and none of exclude,
+// within budget bytes. It prints what was picked and asks before attaching
+// a large total.
+func (s *session) attachFiles(include, exclude []string, limit, budget int) error {
+    sizes := map[string]int64{}
+    for _, pattern := range include {
+        matches, err := s.app.globMatches(pattern)
+        if err != nil {
+            return fmt.Errorf("%s: %w", pattern, err)
+        }
+        if len(matches) == 0 {
+            fmt.Printf("Warning: %s matched no files.\n", pattern)
+        }
+        for p, size := range matches {
+            sizes[p] = size
+        }
+    }
+    var paths []string
+    excluded := 0
+    for p := range sizes {
+        if isExcluded(p, exclude) {
+            excluded++
+            continue
+        }
+        paths = append(paths, p)
+    }
+    if len(paths) == 0 {
+        return fmt.Errorf("no files to attach")
+    }
+    sort.Strings(paths)
+    ignored := gitIgnored(paths)
+
+    type pick struct {
+        path, content, status string
+    }
+    var picks []pick
+    total, skipped := 0, 0
+    for _, p := range paths {
+        if ignored[p] {
+            skipped++
+            continue
+        }
+        if total+int(sizes[p]) > budget {
+            picks = append(picks, pick{path: p, status: "budget"})
+            continue
+        }
+        content, truncated, err := s.app.providerRead(p, 0, limit)
+        switch {
+        case err != nil:
+            picks = append(picks, pick{path: p, status: "error"})
+            logrus.WithError(err).WithField("path", p).Warn("attach: read failed")
+            continue
+        case isBinary(content):
+            picks = append(picks, pick{path: p, status: "binary"})
+            continue
+        case total+len(content) > budget:
+            picks = append(picks, pick{path: p, status: "budget"})
+            continue
+        }
+        total += len(content)
+        status := "attach"
+        if truncated {
+            status = "truncated"
+        }
+        picks = append(picks, pick{path: p, content: content, status: status})
+    }
+
+    n, est := 0, 0
+    for _, pk := range picks {
+        size := "-"
+        if pk.content != "" {
+            size = formatSize(len(pk.content))
+            est += tokens.Count(pk.content)
+            n++
+        }
+        fmt.Printf("  %-9s %10s  %s\n", pk.status, size, pk.path)
+    }
+    fmt.Printf("%d files, %s (~%d tokens)", n, formatSize(total), est)
+    var notes []string
+    if skipped > 0 {
+        notes = append(notes, fmt.Sprintf("%d git-ignored", skipped))
+    }
+    if excluded > 0 {
+        notes = append(notes, fmt.Sprintf("%d excluded", excluded))
+    }
+    if len(notes) > 0 {
+        fmt.Printf("; skipped %s", strings.Join(notes, ", "))
+    }
+    fmt.Println(".")
+    if n == 0 {
+        return fmt.Errorf("nothing to attach")
+    }
+    if total > confirmAttachBytes && !s.confirm(fmt.Sprintf("Attach %d files (%s)?", n, formatSize(total))) {
+        fmt.Println("Not attached.")
+        return nil
+    }
+    for _, pk := range picks {
+        if pk.content == "" {
+            continue
+        }
+        path := pk.path
+        s.attach(path, pk.content, func() (string, error) {
+            contents, _, err := s.app.providerRead(path, 0, limit)
+            return contents, err
+        })
+    }
+    fmt.Printf("Attached %d files.\n", n)
+    logrus.WithFields(logrus.Fields{"include": include, "exclude": exclude, "files": n, "bytes": total, "ignored": skipped}).Info(":attach")
+    return nil
+}
+
+func isExcluded(path string, exclude []string) bool {
+    for _, pattern := range exclude {
+        if glob.Excluded(filepath.ToSlash(pattern), filepath.ToSlash(path)) {
+            return true
+        }
+    }
+    return false
+}
+
+// isBinary reports whether provider content is binary: the provider's
+// placeholder for it, or text with NUL bytes.
+func isBinary(content string) bool {
+    return content == "<binary omitted>" || strings.ContainsRune(content, 0)
+}
+
+// gitIgnored returns which of paths git ignores. Outside a git work tree,
+// or without git, nothing is ignored.
+func gitIgnored(paths []string) map[string]bool {
+    ignored := map[string]bool{}
+    if len(paths) == 0 {
+        return ignored
+    }
+    cmd := exec.Command("git", "check-ignore", "--stdin")
+    cmd.Stdin = strings.NewReader(strings.Join(paths, "\n") + "\n")
+    out, err := cmd.Output()
+    var exitErr *exec.ExitError
+    if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 1) {
+        // Exit code 1 only means nothing is ignored.
+        logrus.WithError(err).Debug("git check-ignore unavailable")
+        return ignored
+    }
+    for _, l := range strings.Split(string(out), "\n") {
+        if l != "" {
+            ignored[filepath.Clean(l)] = true
+        }
+    }
+    return ignored
+}
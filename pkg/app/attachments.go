@@ -0,0 +1,164 @@
+package app
+
+import (
+    "fmt"
+    "strconv"
+
+    "github.com/sirupsen/logrus"
+
+    "gg/internal/tokens"
+)
+
+// defaultAttachHeader introduces attachments in a prompt; override it with
+// attachment_header in the config file.
+const defaultAttachHeader = "You have access to the following context files. Use them when answering."
+
+// Commands to inspect and manage attachments.
+func init() {
+    RegisterCommand(Command{
+        Name: "attachments",
+        Help: "list attachments with size, estimated tokens and state",
+        Run: func(c *Context) error {
+            c.s.printAttachments()
+            return nil
+        },
+    })
+    RegisterCommand(Command{
+        Name: "detach",
+        Help: "remove attachment N",
+        Args: []ArgSpec{{Name: "N"}},
+        Run: func(c *Context) error {
+            i, err := c.s.attachmentIndex(c.Args.Arg(0))
+            if err != nil {
+                return err
+            }
+            path := c.s.attachments[i].path
+            c.s.attachments = append(c.s.attachments[:i], c.s.attachments[i+1:]...)
+            fmt.Printf("Detached %s.\n", path)
+            return nil
+        },
+    })
+    RegisterCommand(Command{
+        Name: "pin",
+        Help: "resend attachment N on later turns whenever it changes",
+        Long: "Pin attachment N: before every turn it is re-read from its source and sent again if its content changed since it was last sent. Unchanged pinned files are not pasted again. Undo with :once N.",
+        Args: []ArgSpec{{Name: "N"}},
+        Run: func(c *Context) error {
+            return c.s.setPinned(c.Args.Arg(0), true)
+        },
+    })
+    RegisterCommand(Command{
+        Name: "once",
+        Help: "send attachment N (or all, without N) only once",
+        Args: []ArgSpec{{Name: "N", Optional: true}},
+        Run: func(c *Context) error {
+            if c.Args.Arg(0) == "" {
+                for i := range c.s.attachments {
+                    c.s.attachments[i].pinned = false
+                }
+                fmt.Println("All attachments are sent once.")
+                return nil
+            }
+            return c.s.setPinned(c.Args.Arg(0), false)
+        },
+    })
+}
+
+// attach adds an attachment for the next turn. reload re-reads it for
+// :pin; it may be nil. Attaching a path again replaces the earlier one.
+func (s *session) attach(path, content string, reload func() (string, error)) {
+    for i := range s.attachments {
+        if at := &s.attachments[i]; at.path == path {
+            at.content, at.reload, at.sent = content, reload, false
+            return
+        }
+    }
+    s.attachments = append(s.attachments, attachment{path: path, content: content, reload: reload})
+}
+
+// attachmentIndex resolves a 1-based attachment number.
+func (s *session) attachmentIndex(arg string) (int, error) {
+    if len(s.attachments) == 0 {
+        return 0, fmt.Errorf("no attachments")
+    }
+    n, err := strconv.Atoi(arg)
+    if err != nil || n < 1 || n > len(s.attachments) {
+        return 0, fmt.Errorf("no attachment %q (1-%d; see :attachments)", arg, len(s.attachments))
+    }
+    return n - 1, nil
+}
+
+func (s *session) setPinned(arg string, pinned bool) error {
+    i, err := s.attachmentIndex(arg)
+    if err != nil {
+        return err
+    }
+    at := &s.attachments[i]
+    if pinned && at.reload == nil {
+        return fmt.Errorf("%s cannot be re-read, so it cannot be pinned", at.path)
+    }
+    at.pinned = pinned
+    if pinned {
+        fmt.Printf("Pinned %s; it is sent again whenever it changes.\n", at.path)
+    } else {
+        fmt.Printf("%s is sent once.\n", at.path)
+    }
+    return nil
+}
+
+// refreshPinned re-reads pinned attachments that were already sent and
+// queues those whose content changed.
+func (s *session) refreshPinned() {
+    for i := range s.attachments {
+        at := &s.attachments[i]
+        if !at.pinned || !at.sent || at.reload == nil {
+            continue
+        }
+        content, err := at.reload()
+        if err != nil {
+            fmt.Printf("Warning: could not re-read pinned %s: %v\n", at.path, err)
+            continue
+        }
+        if content == at.content {
+            continue
+        }
+        at.content, at.sent = content, false
+        fmt.Printf("%s changed; sending it again.\n", at.path)
+        logrus.WithFields(logrus.Fields{"path": at.path, "chars": len(content)}).Info("pinned attachment changed")
+    }
+}
+
+// printAttachments shows the attachments for :attachments.
+func (s *session) printAttachments() {
+    if len(s.attachments) == 0 {
+        fmt.Println("No attachments.")
+        return
+    }
+    fmt.Printf("%3s  %9s  %8s  %-6s  %-7s  %s\n", "#", "size", "~tokens", "mode", "state", "path")
+    total, pending := 0, 0
+    for i, at := range s.attachments {
+        mode, state := "once", "pending"
+        if at.pinned {
+            mode = "pinned"
+        }
+        if at.sent {
+            state = "sent"
+        } else {
+            pending += tokens.Count(at.content)
+        }
+        total += len(at.content)
+        fmt.Printf("%3d  %9s  %8d  %-6s  %-7s  %s\n", i+1, formatSize(len(at.content)), tokens.Count(at.content), mode, state, at.path)
+    }
+    fmt.Printf("Total %s; ~%d tokens pending for the next turn (limit %d).\n", formatSize(total), pending, s.app.promptLimit())
+}
+
+// formatSize renders a byte count, e.g. "812 B" or "12.4 KiB".
+func formatSize(n int) string {
+    switch {
+    case n < 1024:
+        return fmt.Sprintf("%d B", n)
+    case n < 1024*1024:
+        return fmt.Sprintf("%.1f KiB", float64(n)/1024)
+    }
+    return fmt.Sprintf("%.1f MiB", float64(n)/(1024*1024))
+}
@@ -0,0 +1,227 @@
+package app
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+
+    "github.com/sirupsen/logrus"
+
+    "gg/internal/codeblock"
+    "gg/internal/diff"
+)
+
+// Commands for the fenced code blocks of the last answer.
+func init() {
+    RegisterCommand(Command{
+        Name: "blocks",
+        Help: "list the code blocks in the last answer",
+        Run: func(c *Context) error {
+            blocks := codeblock.Extract(c.s.lastAnswer)
+            if len(blocks) == 0 {
+                fmt.Println("No code blocks in the last answer.")
+                return nil
+            }
+            for i, b := range blocks {
+                lang := b.Lang
+                if lang == "" {
+                    lang = "-"
+                }
+                fmt.Printf("%3d  %-12s %4d lines  %s\n", i+1, lang, b.Lines(), blockPreview(b))
+            }
+            return nil
+        },
+    })
+    RegisterCommand(Command{
+        Name: "copy",
+        Help: "copy code block N of the last answer to the clipboard",
+        Args: []ArgSpec{{Name: "N"}},
+        Run: func(c *Context) error {
+            b, err := c.s.block(c.Args.Arg(0))
+            if err != nil {
+                return err
+            }
+            method, err := copyToClipboard(b.Code)
+            if err != nil {
+                return err
+            }
+            fmt.Printf("Copied block %s (%d lines) via %s.\n", c.Args.Arg(0), b.Lines(), method)
+            return nil
+        },
+    })
+    RegisterCommand(Command{
+        Name: "write",
+        Help: "write code block N to a file, after showing a diff",
+        Long: "Write code block N of the last answer to path. The change is shown as a diff against the existing file and needs confirmation. Writes go through the MCP provider: the path must be inside its roots and allow_write must be enabled in mcp.toml.",
+        Args: []ArgSpec{{Name: "N"}, {Name: "path", Path: true}},
+        Run: func(c *Context) error {
+            b, err := c.s.block(c.Args.Arg(0))
+            if err != nil {
+                return err
+            }
+            path, err := filepath.Abs(c.Args.Arg(1))
+            if err != nil {
+                return err
+            }
+            if _, err := c.s.app.providerWrite(path, b.Code, true, true); err != nil {
+                return err
+            }
+            old, exists, err := c.s.app.readForWrite(path)
+            if err != nil {
+                return err
+            }
+            if exists && old == b.Code {
+                fmt.Println("No changes.")
+                return nil
+            }
+            printDiff(path, old, b.Code, exists)
+            if !c.s.confirm(fmt.Sprintf("Write %d lines to %s?", b.Lines(), path)) {
+                fmt.Println("Not written.")
+                return nil
+            }
+            if _, err := c.s.app.providerWrite(path, b.Code, true, false); err != nil {
+                return err
+            }
+            fmt.Printf("Wrote %s.\n", path)
+            return nil
+        },
+    })
+    RegisterCommand(Command{
+        Name: "save-code",
+        Help: "write all code blocks of the last answer into a directory",
+        Long: "Write every code block of the last answer into dir, using the file name hinted in the fence (```go main.go or title=\"main.go\") or block-N.<ext>. Shows what will change and asks once. The same MCP root and allow_write rules as :write apply.",
+        Args: []ArgSpec{{Name: "dir", Path: true}},
+        Run: func(c *Context) error {
+            blocks := codeblock.Extract(c.s.lastAnswer)
+            if len(blocks) == 0 {
+                return fmt.Errorf("no code blocks in the last answer")
+            }
+            dir, err := filepath.Abs(c.Args.Arg(0))
+            if err != nil {
+                return err
+            }
+            type plan struct {
+                path string
+                code string
+            }
+            var plans []plan
+            for i, name := range blockFileNames(blocks) {
+                path := filepath.Join(dir, name)
+                if _, err := c.s.app.providerWrite(path, blocks[i].Code, true, true); err != nil {
+                    return fmt.Errorf("%s: %w", path, err)
+                }
+                old, exists, err := c.s.app.readForWrite(path)
+                if err != nil {
+                    return fmt.Errorf("%s: %w", path, err)
+                }
+                status := "new"
+                switch {
+                case exists && old == blocks[i].Code:
+                    fmt.Printf("  unchanged  %s\n", name)
+                    continue
+                case exists:
+                    added, removed := diff.Stat(diff.Unified(path, path, old, blocks[i].Code, 0))
+                    status = fmt.Sprintf("+%d -%d", added, removed)
+                }
+                fmt.Printf("  %-9s  %s\n", status, name)
+                plans = append(plans, plan{path, blocks[i].Code})
+            }
+            if len(plans) == 0 {
+                fmt.Println("Nothing to write.")
+                return nil
+            }
+            if !c.s.confirm(fmt.Sprintf("Write %d files to %s?", len(plans), dir)) {
+                fmt.Println("Not written.")
+                return nil
+            }
+            for _, p := range plans {
+                if _, err := c.s.app.providerWrite(p.path, p.code, true, false); err != nil {
+                    return fmt.Errorf("%s: %w", p.path, err)
+                }
+            }
+            fmt.Printf("Wrote %d files.\n", len(plans))
+            return nil
+        },
+    })
+}
+
+// block returns code block arg (1-based) of the last answer.
+func (s *session) block(arg string) (codeblock.Block, error) {
+    blocks := codeblock.Extract(s.lastAnswer)
+    if len(blocks) == 0 {
+        return codeblock.Block{}, fmt.Errorf("no code blocks in the last answer")
+    }
+    n, err := strconv.Atoi(arg)
+    if err != nil || n < 1 || n > len(blocks) {
+        return codeblock.Block{}, fmt.Errorf("no block %q (1-%d; see :blocks)", arg, len(blocks))
+    }
+    return blocks[n-1], nil
+}
+
+func blockPreview(b codeblock.Block) string {
+    if name := b.Filename(); name != "" {
+        return name
+    }
+    for _, l := range strings.Split(b.Code, "\n") {
+        if l = strings.TrimSpace(l); l != "" {
+            if r := []rune(l); len(r) > 50 {
+                l = string(r[:47]) + "..."
+            }
+            return l
+        }
+    }
+    return ""
+}
+
+// blockFileNames picks a file name for each block: the hinted name if it is
+// a local relative path, otherwise block-N.<ext>. Duplicates get a suffix.
+func blockFileNames(blocks []codeblock.Block) []string {
+    names := make([]string, len(blocks))
+    used := map[string]bool{}
+    for i, b := range blocks {
+        name := filepath.FromSlash(b.Filename())
+        if name == "" || !filepath.IsLocal(name) {
+            name = fmt.Sprintf("block-%d%s", i+1, b.Ext())
+        }
+        base, ext := strings.TrimSuffix(name, filepath.Ext(name)), filepath.Ext(name)
+        for n := 2; used[name]; n++ {
+            name = fmt.Sprintf("%s-%d%s", base, n, ext)
+        }
+        used[name] = true
+        names[i] = name
+    }
+    return names
+}
+
+// printDiff shows the change from old to new, colored on a terminal.
+func printDiff(path, old, new string, exists bool) {
+    from := path
+    if !exists {
+        from = "/dev/null"
+    }
+    d := diff.Unified(from, path, old, new, 3)
+    if stdoutIsTerminal() {
+        d = diff.Colorize(d)
+    }
+    fmt.Print(d)
+}
+
+// readForWrite returns a file's current content before it is overwritten,
+// reading through the provider so the MCP roots apply. A missing file is
+// not an error. Truncated reads are refused, since the diff would be wrong.
+func (a *App) readForWrite(path string) (string, bool, error) {
+    if _, err := os.Stat(path); os.IsNotExist(err) {
+        return "", false, nil
+    }
+    old, truncated, err := a.providerRead(path, 0, 0)
+    if err != nil {
+        return "", true, err
+    }
+    if truncated {
+        return "", true, fmt.Errorf("%s is larger than max_bytes; not overwriting", path)
+    }
+    logrus.WithFields(logrus.Fields{"path": path, "bytes": len(old)}).Debug("read before write")
+    return old, true, nil
+}
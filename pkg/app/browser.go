@@ -0,0 +1,207 @@
+package app
+
+import (
+    "context"
+    "errors"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/chromedp/cdproto/inspector"
+    "github.com/chromedp/cdproto/target"
+    "github.com/chromedp/chromedp"
+    "github.com/sirupsen/logrus"
+)
+
+const chatURL = `https://chatgpt.com`
+
+// maxRelaunches bounds how many times a single prompt is retried after the
+// browser is lost, so a browser that dies on startup can't loop forever.
+const maxRelaunches = 3
+
+// browser owns the chromedp allocator/tab contexts for a session and notices
+// when Chrome crashes or its window is closed, so the session can relaunch it.
+type browser struct {
+    opts       []chromedp.ExecAllocatorOption
+    profileDir string
+    // remoteURL is set when attached to a Chrome that already owns the profile.
+    remoteURL string
+
+    mu      sync.Mutex
+    ctx     context.Context // task context used for chromedp.Run
+    cancels []context.CancelFunc
+    lost    chan struct{}
+}
+
+// allocatorOptions returns the Chrome flags shared by chat and login sessions.
+func (a *App) allocatorOptions() []chromedp.ExecAllocatorOption {
+    return append(chromedp.DefaultExecAllocatorOptions[:],
+        chromedp.ExecPath(a.defaultBrowser),
+        chromedp.Flag("disable-blink-features", "AutomationControlled"),
+        chromedp.Flag("exclude-switches", "enable-automation"),
+        chromedp.Flag("disable-extensions", false),
+        chromedp.UserAgent("Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"),
+        chromedp.Flag("disable-default-apps", false),
+        chromedp.Flag("disable-dev-shm-usage", false),
+        chromedp.Flag("disable-gpu", false),
+        chromedp.Flag("headless", false),
+        chromedp.UserDataDir(a.profileDir),
+        chromedp.Flag("profile-directory", "Default"),
+    )
+}
+
+func newBrowser(opts []chromedp.ExecAllocatorOption, profileDir, remoteURL string) *browser {
+    return &browser{opts: opts, profileDir: profileDir, remoteURL: remoteURL}
+}
+
+// launch starts Chrome and attaches loss watchers to the new tab.
+func (b *browser) launch() error {
+    var allocCtx context.Context
+    var allocCancel context.CancelFunc
+    if b.remoteURL != "" {
+        allocCtx, allocCancel = chromedp.NewRemoteAllocator(context.Background(), b.remoteURL)
+    } else {
+        allocCtx, allocCancel = chromedp.NewExecAllocator(context.Background(), b.opts...)
+    }
+    ctx, cancel := chromedp.NewContext(allocCtx)
+    taskCtx, taskCancel := context.WithTimeout(ctx, ctxTime*time.Second)
+
+    lost := make(chan struct{})
+    var once sync.Once
+    markLost := func(reason string) {
+        once.Do(func() {
+            logrus.WithField("reason", reason).Warn("browser lost")
+            close(lost)
+        })
+    }
+
+    chromedp.ListenTarget(ctx, func(ev any) {
+        switch e := ev.(type) {
+        case *inspector.EventTargetCrashed:
+            markLost("target crashed")
+        case *inspector.EventDetached:
+            markLost("detached: " + e.Reason.String())
+        }
+    })
+
+    // First Run allocates the browser and the tab.
+    if err := chromedp.Run(taskCtx); err != nil {
+        taskCancel()
+        cancel()
+        allocCancel()
+        return err
+    }
+
+    tabID := chromedp.FromContext(ctx).Target.TargetID
+    chromedp.ListenBrowser(ctx, func(ev any) {
+        switch e := ev.(type) {
+        case *target.EventTargetDestroyed:
+            if e.TargetID == tabID {
+                markLost("tab closed")
+            }
+        case *target.EventTargetCrashed:
+            if e.TargetID == tabID {
+                markLost("tab crashed: " + e.Status)
+            }
+        }
+    })
+    // chromedp cancels the tab context when the websocket to Chrome drops.
+    go func() {
+        select {
+        case <-ctx.Done():
+            markLost("connection closed")
+        case <-lost:
+        }
+    }()
+
+    b.mu.Lock()
+    b.ctx = taskCtx
+    b.cancels = []context.CancelFunc{taskCancel, cancel, allocCancel}
+    b.lost = lost
+    b.mu.Unlock()
+    logrus.Debug("browser launched")
+    return nil
+}
+
+// Ctx returns the current task context.
+func (b *browser) Ctx() context.Context {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    return b.ctx
+}
+
+// Lost reports whether the current browser instance has gone away.
+func (b *browser) Lost() bool {
+    b.mu.Lock()
+    lost := b.lost
+    b.mu.Unlock()
+    if lost == nil {
+        return true
+    }
+    select {
+    case <-lost:
+        return true
+    default:
+        return false
+    }
+}
+
+// relaunch tears down the current instance and starts a fresh one, then
+// navigates to url (the current conversation, if any).
+func (b *browser) relaunch(url string) error {
+    b.close()
+    // If the browser we attached to has exited, the profile is ours now.
+    if b.remoteURL != "" {
+        if lock, err := readProfileLock(b.profileDir); err == nil && (lock == nil || !lock.alive()) {
+            b.remoteURL = ""
+        }
+    }
+    if err := b.launch(); err != nil {
+        return err
+    }
+    return chromedp.Run(b.Ctx(), chromedp.Navigate(url))
+}
+
+// close releases all contexts of the current instance.
+func (b *browser) close() {
+    b.mu.Lock()
+    cancels := b.cancels
+    b.cancels = nil
+    b.mu.Unlock()
+    for _, c := range cancels {
+        c()
+    }
+}
+
+// conversationURL returns the URL to restore after a relaunch.
+func conversationURL(id string) string {
+    if id == "" {
+        return chatURL
+    }
+    return chatURL + "/c/" + id
+}
+
+// conversationIDFromURL extracts the id from https://chatgpt.com/c/<id>.
+func conversationIDFromURL(u string) string {
+    i := strings.Index(u, "/c/")
+    if i < 0 {
+        return ""
+    }
+    id := u[i+len("/c/"):]
+    if j := strings.IndexAny(id, "/?#"); j >= 0 {
+        id = id[:j]
+    }
+    return id
+}
+
+// isBrowserGone reports whether err looks like the browser/tab went away
+// rather than a page-level failure.
+func (b *browser) isBrowserGone(err error) bool {
+    if err == nil {
+        return false
+    }
+    if b.Lost() {
+        return true
+    }
+    return errors.Is(err, context.Canceled) || errors.Is(err, chromedp.ErrInvalidContext)
+}
@@ -0,0 +1,199 @@
+package app
+
+import (
+    "encoding/json"
+    "fmt"
+    "path/filepath"
+
+    "github.com/sirupsen/logrus"
+
+    "gg/internal/sessions"
+)
+
+// Built-in local commands.
+func init() {
+    RegisterCommand(Command{
+        Name:    "help",
+        Aliases: []string{"?"},
+        Help:    "list commands, or show details for one",
+        Args:    []ArgSpec{{Name: "command", Optional: true}},
+        Run: func(c *Context) error {
+            return printHelp(c.Args.Arg(0))
+        },
+    })
+    RegisterCommand(Command{
+        Name: "clear",
+        Help: "remove all attachments",
+        Run: func(c *Context) error {
+            c.s.attachments = c.s.attachments[:0]
+            fmt.Println("Attachments cleared.")
+            return nil
+        },
+    })
+    RegisterCommand(Command{
+        Name: "attach",
+        Help: "attach files to the next message",
+        Long: "Read files through the MCP provider and send them as context with the next message. path#L120-200 attaches a line range and path::Name a function, method, type or class (Type.Method to qualify), numbered by line. Paths may be globs (internal/**/*.go); words starting with ! exclude matches (!*_test.go). A directory attaches the files under it. Multi-file attachments skip binary and git-ignored files, stop at the byte budget, and ask for confirmation when large.",
+        Args: []ArgSpec{{Name: "path", Path: true, Rest: true}},
+        Options: []OptionSpec{
+            {Name: "limit", Value: "N", Help: "read at most N bytes of each file"},
+            {Name: "budget", Value: "BYTES", Help: "total size for multi-file attachments (default 262144)"},
+        },
+        Run: func(c *Context) error {
+            limit, err := c.Args.Int("limit", 0)
+            if err != nil {
+                return err
+            }
+            budget, err := c.Args.Int("budget", defaultAttachBudget)
+            if err != nil {
+                return err
+            }
+            return c.s.attachPaths(c.Args.Values, limit, budget)
+        },
+    })
+    RegisterCommand(Command{
+        Name:    "list",
+        Aliases: []string{"ls"},
+        Help:    "list directory entries",
+        Args:    []ArgSpec{{Name: "path", Optional: true, Path: true}},
+        Options: []OptionSpec{{Name: "depth", Value: "N", Help: "how many levels to descend (default 1)"}},
+        Run: func(c *Context) error {
+            path := c.Args.Arg(0)
+            if path == "" {
+                path = "."
+            }
+            depth, err := c.Args.Int("depth", 1)
+            if err != nil {
+                return err
+            }
+            entries, err := c.s.app.providerList(path, depth)
+            if err != nil {
+                return err
+            }
+            logrus.WithFields(logrus.Fields{"path": path, "depth": depth, "count": len(entries)}).Info(":list")
+            for _, e := range entries {
+                name, _ := e["name"].(string)
+                dir, _ := e["dir"].(bool)
+                p, _ := e["path"].(string)
+                if rel, err := filepath.Rel(".", p); err == nil {
+                    p = rel
+                }
+                if dir {
+                    fmt.Printf("[D] %s\t%s\n", name, p)
+                } else {
+                    fmt.Printf("[F] %s\t%s\n", name, p)
+                }
+            }
+            return nil
+        },
+    })
+    RegisterCommand(Command{
+        Name:    "search",
+        Help:    "search file contents under a root",
+        Args:    []ArgSpec{{Name: "root", Path: true}, {Name: "query", Rest: true}},
+        Options: []OptionSpec{{Name: "globs", Value: "pat1,pat2", Help: "only search files matching these patterns"}},
+        Run: func(c *Context) error {
+            root, query := c.Args.Arg(0), c.Args.Arg(1)
+            globs := c.Args.List("globs")
+            matches, err := c.s.app.providerSearch(root, query, globs)
+            if err != nil {
+                return err
+            }
+            logrus.WithFields(logrus.Fields{"root": root, "query": query, "globs": globs, "count": len(matches)}).Info(":search")
+            for _, m := range matches {
+                p, _ := m["path"].(string)
+                mt, _ := m["mimeType"].(string)
+                if rel, err := filepath.Rel(".", p); err == nil {
+                    p = rel
+                }
+                fmt.Printf("- %s (%s)\n", p, mt)
+            }
+            return nil
+        },
+    })
+    RegisterCommand(Command{
+        Name: "stat",
+        Help: "show file or directory metadata",
+        Args: []ArgSpec{{Name: "path", Path: true}},
+        Run: func(c *Context) error {
+            info, err := c.s.app.providerStat(c.Args.Arg(0))
+            if err != nil {
+                return err
+            }
+            logrus.WithFields(logrus.Fields{"path": c.Args.Arg(0)}).Info(":stat")
+            b, _ := json.MarshalIndent(info, "", "  ")
+            fmt.Println(string(b))
+            return nil
+        },
+    })
+    RegisterCommand(Command{
+        Name: "style",
+        Help: "show or set the response style",
+        Long: "Show or set the response style applied to every prompt: concise, detailed, code-only, none, or custom:<text>.",
+        Args: []ArgSpec{{Name: "style", Optional: true, Rest: true, Values: []string{"concise", "detailed", "code-only", "none", "custom:"}}},
+        Run: func(c *Context) error {
+            if c.Args.Arg(0) == "" {
+                fmt.Printf("Style: %s\n", c.s.style)
+                return nil
+            }
+            st, err := parseStyle(c.Args.Arg(0))
+            if err != nil {
+                return err
+            }
+            c.s.style = st
+            fmt.Printf("Style set to %s.\n", st)
+            logrus.WithField("style", st.String()).Info(":style")
+            return nil
+        },
+    })
+    RegisterCommand(Command{
+        Name: "status",
+        Help: "show model, style, turns, attachments and conversation",
+        Run: func(c *Context) error {
+            c.s.printStatus()
+            return nil
+        },
+    })
+    RegisterCommand(Command{
+        Name: "edit",
+        Help: "compose a prompt in $EDITOR, pre-filled with the last prompt",
+        Run: func(c *Context) error {
+            text, err := c.s.editPrompt()
+            if err != nil {
+                return err
+            }
+            if text == "" {
+                fmt.Println("Empty prompt; nothing sent.")
+                return nil
+            }
+            if err := c.s.editor.AddHistory(text); err != nil {
+                logrus.WithError(err).Warn("failed to save history")
+            }
+            c.s.userTurn(text)
+            return nil
+        },
+    })
+    RegisterCommand(Command{
+        Name:    "export",
+        Help:    "write this session's transcript to a file",
+        Long:    "Write the transcript of this session as Markdown, self-contained HTML or JSON. The format defaults to the file extension (.md, .html, .json).",
+        Args:    []ArgSpec{{Name: "file"}},
+        Options: []OptionSpec{{Name: "format", Value: "md|html|json", Help: "output format (default: from the extension)"}},
+        Run: func(c *Context) error {
+            path := c.Args.Arg(0)
+            format, ok := c.Args.Option("format")
+            if !ok {
+                format = sessions.FormatForPath(path)
+            }
+            if len(c.s.log) == 0 {
+                return fmt.Errorf("nothing to export yet")
+            }
+            if err := sessions.ExportFile(path, c.s.id, c.s.log, format); err != nil {
+                return err
+            }
+            fmt.Printf("Exported %d turns to %s (%s).\n", len(c.s.log), path, format)
+            logrus.WithFields(logrus.Fields{"file": path, "format": format, "turns": len(c.s.log)}).Info(":export")
+            return nil
+        },
+    })
+}
@@ -0,0 +1,50 @@
+package app
+
+import (
+    "encoding/base64"
+    "errors"
+    "fmt"
+    "os"
+    "os/exec"
+    "runtime"
+    "strings"
+)
+
+// clipboardTools are tried in order; each needs its condition to hold.
+var clipboardTools = []struct {
+    name string
+    args []string
+    when func() bool
+}{
+    {"pbcopy", nil, func() bool { return runtime.GOOS == "darwin" }},
+    {"wl-copy", nil, func() bool { return os.Getenv("WAYLAND_DISPLAY") != "" }},
+    {"xclip", []string{"-selection", "clipboard"}, func() bool { return os.Getenv("DISPLAY") != "" }},
+    {"xsel", []string{"--clipboard", "--input"}, func() bool { return os.Getenv("DISPLAY") != "" }},
+    {"clip.exe", nil, func() bool { return true }},
+}
+
+// copyToClipboard puts text on the system clipboard with the first available
+// tool, falling back to the OSC 52 terminal escape (which also works over
+// SSH in most terminals). It returns the method used.
+func copyToClipboard(text string) (string, error) {
+    for _, t := range clipboardTools {
+        if !t.when() {
+            continue
+        }
+        path, err := exec.LookPath(t.name)
+        if err != nil {
+            continue
+        }
+        cmd := exec.Command(path, t.args...)
+        cmd.Stdin = strings.NewReader(text)
+        if err := cmd.Run(); err != nil {
+            return t.name, fmt.Errorf("%s: %w", t.name, err)
+        }
+        return t.name, nil
+    }
+    if !stdoutIsTerminal() {
+        return "", errors.New("no clipboard tool found (install xclip, xsel or wl-clipboard)")
+    }
+    fmt.Printf("\x1b]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(text)))
+    return "terminal (OSC 52)", nil
+}
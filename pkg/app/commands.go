@@ -0,0 +1,294 @@
+package app
+
+import (
+    "errors"
+    "fmt"
+    "sort"
+    "strconv"
+    "strings"
+
+    "github.com/sirupsen/logrus"
+
+    "gg/internal/cmdline"
+)
+
+// Command is a local ":" REPL command. Commands are described by their
+// argument specs, from which parsing, usage, :help and Tab completion are
+// derived.
+type Command struct {
+    Name    string
+    Aliases []string
+    // Help is a one-line summary; Long is shown by :help <name>.
+    Help string
+    Long string
+    Args    []ArgSpec
+    Options []OptionSpec
+    Run     func(c *Context) error
+}
+
+// ArgSpec describes a positional argument.
+type ArgSpec struct {
+    Name     string
+    Optional bool
+    // Rest collects all remaining words (joined by spaces) into this argument.
+    Rest bool
+    // Path enables path completion through the MCP provider.
+    Path bool
+    // Values are offered by Tab completion.
+    Values []string
+}
+
+// OptionSpec describes a key=value option.
+type OptionSpec struct {
+    Name string
+    // Value is the placeholder shown in usage, e.g. "N".
+    Value string
+    Help  string
+}
+
+// Args holds a parsed command line.
+type Args struct {
+    Positional []string
+    Options    map[string]string
+    // Words are all words after the command name, unquoted, before
+    // options were separated and Rest arguments joined.
+    Words []string
+    // Values are the positional words before Rest arguments were joined.
+    Values []string
+}
+
+// Arg returns the i-th positional argument or "".
+func (a Args) Arg(i int) string {
+    if i < len(a.Positional) {
+        return a.Positional[i]
+    }
+    return ""
+}
+
+// Option returns the value of a key=value option.
+func (a Args) Option(name string) (string, bool) {
+    v, ok := a.Options[name]
+    return v, ok
+}
+
+// Int returns an integer option, or def if it is absent.
+func (a Args) Int(name string, def int) (int, error) {
+    v, ok := a.Options[name]
+    if !ok {
+        return def, nil
+    }
+    n, err := strconv.Atoi(v)
+    if err != nil {
+        return def, fmt.Errorf("%s must be a number", name)
+    }
+    return n, nil
+}
+
+// List returns a comma-separated option as a slice.
+func (a Args) List(name string) []string {
+    var out []string
+    for _, p := range strings.Split(a.Options[name], ",") {
+        if p = strings.TrimSpace(p); p != "" {
+            out = append(out, p)
+        }
+    }
+    return out
+}
+
+// ErrUsage makes the dispatcher print the command's usage line.
+var ErrUsage = errors.New("usage")
+
+// Context is what a command's Run receives: its arguments and the session.
+type Context struct {
+    Name string // the name or alias the command was invoked with
+    Args Args
+    s    *session
+}
+
+// Send submits prompt as a chat turn and prints the answer.
+func (c *Context) Send(prompt string) { c.s.turn(prompt) }
+
+// Attach adds a context file for the next turn.
+func (c *Context) Attach(path, content string) {
+    c.s.attach(path, content, nil)
+}
+
+// LastPrompt returns the last prompt sent in this session.
+func (c *Context) LastPrompt() string { return c.s.lastPrompt }
+
+// LastAnswer returns the last answer received in this session.
+func (c *Context) LastAnswer() string { return c.s.lastAnswer }
+
+var (
+    commands = map[string]*Command{}
+    aliases  = map[string]string{}
+)
+
+// RegisterCommand makes a ":" command available in the REPL. Names and
+// aliases are case-insensitive and stored lowercased. Registering an
+// existing name replaces it.
+func RegisterCommand(c Command) {
+    cmd := c
+    cmd.Name = strings.ToLower(cmd.Name)
+    cmd.Aliases = make([]string, len(c.Aliases))
+    for i, al := range c.Aliases {
+        cmd.Aliases[i] = strings.ToLower(al)
+    }
+    commands[cmd.Name] = &cmd
+    for _, al := range cmd.Aliases {
+        aliases[al] = cmd.Name
+    }
+}
+
+// lookupCommand resolves a name or alias.
+func lookupCommand(name string) *Command {
+    name = strings.ToLower(name)
+    if c, ok := commands[name]; ok {
+        return c
+    }
+    if n, ok := aliases[name]; ok {
+        return commands[n]
+    }
+    return nil
+}
+
+// commandNames returns all command names and aliases, sorted.
+func commandNames() []string {
+    names := make([]string, 0, len(commands)+len(aliases))
+    for n := range commands {
+        names = append(names, n)
+    }
+    for a := range aliases {
+        names = append(names, a)
+    }
+    sort.Strings(names)
+    return names
+}
+
+// Usage renders the usage line from the specs, e.g.
+// ":attach <path> [limit=N]".
+func (c *Command) Usage() string {
+    parts := []string{":" + c.Name}
+    for _, a := range c.Args {
+        name := a.Name
+        if a.Rest {
+            name += "..."
+        }
+        if a.Optional {
+            parts = append(parts, "["+name+"]")
+        } else {
+            parts = append(parts, "<"+name+">")
+        }
+    }
+    for _, o := range c.Options {
+        parts = append(parts, "["+o.Name+"="+o.Value+"]")
+    }
+    return strings.Join(parts, " ")
+}
+
+// parse splits words into positional arguments and declared options.
+func (c *Command) parse(words []string) (Args, error) {
+    args := Args{Options: map[string]string{}, Words: words}
+    for _, w := range words {
+        if k, v, ok := strings.Cut(w, "="); ok && c.option(k) != nil {
+            args.Options[k] = v
+            continue
+        }
+        args.Positional = append(args.Positional, w)
+    }
+    args.Values = append([]string(nil), args.Positional...)
+    required := 0
+    rest := false
+    for _, a := range c.Args {
+        if !a.Optional {
+            required++
+        }
+        rest = rest || a.Rest
+    }
+    if len(args.Positional) < required {
+        return args, ErrUsage
+    }
+    if n := len(c.Args); len(args.Positional) > n {
+        if !rest || n == 0 {
+            return args, fmt.Errorf("too many arguments")
+        }
+        joined := strings.Join(args.Positional[n-1:], " ")
+        args.Positional = append(args.Positional[:n-1], joined)
+    }
+    return args, nil
+}
+
+func (c *Command) option(name string) *OptionSpec {
+    for i := range c.Options {
+        if c.Options[i].Name == name {
+            return &c.Options[i]
+        }
+    }
+    return nil
+}
+
+// handleLocalCommand parses and runs a ":" command line. It returns false if
+// the command is unknown.
+func (s *session) handleLocalCommand(line string) bool {
+    words, err := cmdline.Split(strings.TrimPrefix(line, ":"))
+    if err != nil {
+        fmt.Printf("parse error: %v\n", err)
+        return true
+    }
+    if len(words) == 0 {
+        return true
+    }
+    cmd := lookupCommand(words[0])
+    if cmd == nil {
+        return false
+    }
+    args, err := cmd.parse(words[1:])
+    if err == nil {
+        logrus.WithFields(logrus.Fields{"command": cmd.Name, "args": args.Positional}).Debug("local command")
+        err = cmd.Run(&Context{Name: words[0], Args: args, s: s})
+    }
+    switch {
+    case errors.Is(err, ErrUsage):
+        fmt.Printf("Usage: %s\n", cmd.Usage())
+    case err != nil:
+        fmt.Printf("%s error: %v\n", cmd.Name, err)
+    }
+    return true
+}
+
+// printHelp lists all commands, or details one command.
+func printHelp(name string) error {
+    if name != "" {
+        cmd := lookupCommand(strings.TrimPrefix(name, ":"))
+        if cmd == nil {
+            return fmt.Errorf("unknown command %q", name)
+        }
+        fmt.Printf("Usage: %s\n", cmd.Usage())
+        if len(cmd.Aliases) > 0 {
+            fmt.Printf("Aliases: :%s\n", strings.Join(cmd.Aliases, ", :"))
+        }
+        fmt.Println()
+        if cmd.Long != "" {
+            fmt.Println(cmd.Long)
+        } else {
+            fmt.Println(cmd.Help)
+        }
+        for _, o := range cmd.Options {
+            fmt.Printf("  %-16s %s\n", o.Name+"="+o.Value, o.Help)
+        }
+        return nil
+    }
+    names := make([]string, 0, len(commands))
+    for n := range commands {
+        names = append(names, n)
+    }
+    sort.Strings(names)
+    fmt.Println("Commands:")
+    for _, n := range names {
+        c := commands[n]
+        fmt.Printf("  %-44s %s\n", c.Usage(), c.Help)
+    }
+    fmt.Printf("  %-44s %s\n", `"""`, "start/end a multi-line prompt")
+    fmt.Println("Type :help <command> for details.")
+    return nil
+}
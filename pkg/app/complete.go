@@ -0,0 +1,117 @@
+package app
+
+import (
+    "path/filepath"
+    "sort"
+    "strings"
+
+    "gg/internal/cmdline"
+)
+
+// complete is the line editor's Tab handler for the REPL. Candidates come
+// from the command registry: names and aliases, declared options, fixed
+// values, and paths for arguments marked Path.
+func (s *session) complete(line []rune, pos int) ([]string, int) {
+    before := string(line[:pos])
+    if !strings.HasPrefix(before, ":") {
+        return nil, 0
+    }
+    words, last := cmdline.SplitPartial(before)
+    start := len([]rune(before[:last.Start]))
+
+    if len(words) == 0 {
+        cmd := strings.TrimPrefix(last.Text, ":")
+        var out []string
+        for _, c := range commandNames() {
+            if strings.HasPrefix(c, cmd) {
+                out = append(out, ":"+c)
+            }
+        }
+        return out, start
+    }
+
+    cmd := lookupCommand(strings.TrimPrefix(words[0].Text, ":"))
+    if cmd == nil {
+        return nil, 0
+    }
+    // Index of the positional argument being typed, ignoring options.
+    argIndex := 0
+    for _, w := range words[1:] {
+        if k, _, ok := strings.Cut(w.Text, "="); !ok || cmd.option(k) == nil {
+            argIndex++
+        }
+    }
+    // Further words still belong to a trailing Rest argument.
+    if n := len(cmd.Args); argIndex >= n && n > 0 && cmd.Args[n-1].Rest {
+        argIndex = n - 1
+    }
+    word := last.Text
+    var out []string
+    if last.Quote == 0 {
+        for _, o := range cmd.Options {
+            if strings.HasPrefix(o.Name+"=", word) && (argIndex > 0 || word != "") {
+                out = append(out, o.Name+"=")
+            }
+        }
+    }
+    if argIndex < len(cmd.Args) {
+        spec := cmd.Args[argIndex]
+        for _, v := range spec.Values {
+            if strings.HasPrefix(v, word) {
+                out = append(out, v)
+            }
+        }
+        if spec.Path && !strings.Contains(word, "=") {
+            for _, p := range s.completePath(word) {
+                out = append(out, quoteCandidate(p, last.Quote))
+            }
+        }
+    }
+    return out, start
+}
+
+// quoteCandidate re-quotes a path candidate when it needs quoting or the user
+// opened a quote. Directories stay open so completion can continue.
+func quoteCandidate(p string, quote rune) string {
+    if quote == 0 && cmdline.Quote(p) == p {
+        return p
+    }
+    q := string(quote)
+    if quote == 0 {
+        q = `"`
+    }
+    escaped := strings.NewReplacer(`\`, `\\`, q, `\`+q).Replace(p)
+    if strings.HasSuffix(p, "/") {
+        return q + escaped
+    }
+    return q + escaped + q
+}
+
+// completePath lists entries matching word through the MCP provider, so only
+// paths inside the allowed roots are offered.
+func (s *session) completePath(word string) []string {
+    dir, prefix := ".", word
+    shown := ""
+    if i := strings.LastIndex(word, "/"); i >= 0 {
+        dir, prefix, shown = word[:i+1], word[i+1:], word[:i+1]
+    }
+    entries, err := s.app.providerList(dir, 1)
+    if err != nil {
+        return nil
+    }
+    var out []string
+    for _, e := range entries {
+        name, _ := e["name"].(string)
+        p, _ := e["path"].(string)
+        // fs.list may include deeper levels; keep direct children only.
+        if name == "" || !strings.HasPrefix(name, prefix) || filepath.Dir(p) != filepath.Clean(dir) {
+            continue
+        }
+        if isDir, _ := e["dir"].(bool); isDir {
+            name += "/"
+        }
+        out = append(out, shown+name)
+    }
+    sort.Strings(out)
+    return out
+}
@@ -0,0 +1,194 @@
+package app
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "strings"
+
+    "github.com/sirupsen/logrus"
+)
+
+// extCmdPrefix names executables on PATH that become ":" commands.
+const extCmdPrefix = "chatbang-cmd-"
+
+// extRequest is the session context sent as JSON on an external command's stdin.
+type extRequest struct {
+    Command         string          `json:"command"`
+    Args            []string        `json:"args"`
+    LastPrompt      string          `json:"last_prompt"`
+    LastAnswer      string          `json:"last_answer"`
+    Attachments     []extAttachment `json:"attachments"`
+    ConversationID  string          `json:"conversation_id"`
+    ConversationURL string          `json:"conversation_url"`
+    Model           string          `json:"model"`
+    Cwd             string          `json:"cwd"`
+}
+
+type extAttachment struct {
+    Path    string `json:"path"`
+    Content string `json:"content,omitempty"`
+}
+
+// extResponse is what an external command may print as JSON on stdout.
+// Any other output (including JSON without these keys) is printed as text.
+type extResponse struct {
+    Print  string          `json:"print"`
+    Attach []extAttachment `json:"attach"`
+    Prompt string          `json:"prompt"`
+}
+
+// externalCommandDirs returns where external commands are looked up: the
+// user's commands directory first, then PATH.
+func (a *App) externalCommandDirs() []string {
+    dirs := []string{filepath.Join(a.configDir, "commands")}
+    return append(dirs, filepath.SplitList(os.Getenv("PATH"))...)
+}
+
+// discoverExternalCommands maps command names to executables. In the commands
+// directory any executable counts (named <name> or chatbang-cmd-<name>); on
+// PATH only chatbang-cmd-<name>. Earlier directories win.
+func discoverExternalCommands(dirs []string, userDir string) map[string]string {
+    found := map[string]string{}
+    for _, dir := range dirs {
+        entries, err := os.ReadDir(dir)
+        if err != nil {
+            continue
+        }
+        for _, e := range entries {
+            name := e.Name()
+            if !strings.HasPrefix(name, extCmdPrefix) && dir != userDir {
+                continue
+            }
+            // Commands are looked up lowercased, so Deploy runs as :deploy.
+            cmd := strings.ToLower(strings.TrimPrefix(name, extCmdPrefix))
+            if cmd == "" || strings.HasPrefix(cmd, ".") {
+                continue
+            }
+            if _, ok := found[cmd]; ok {
+                continue
+            }
+            path := filepath.Join(dir, name)
+            info, err := os.Stat(path)
+            if err != nil || info.IsDir() || info.Mode()&0o111 == 0 {
+                continue
+            }
+            found[cmd] = path
+        }
+    }
+    return found
+}
+
+// registerExternalCommands adds discovered executables to the registry.
+// Built-in and package-registered commands keep precedence.
+func (a *App) registerExternalCommands() {
+    dirs := a.externalCommandDirs()
+    for name, path := range discoverExternalCommands(dirs, dirs[0]) {
+        if lookupCommand(name) != nil {
+            logrus.WithFields(logrus.Fields{"command": name, "path": path}).Debug("external command shadowed by built-in")
+            continue
+        }
+        path := path
+        RegisterCommand(Command{
+            Name: name,
+            Help: "external: " + path,
+            Long: "Runs " + path + " with the session context as JSON on stdin.",
+            Args: []ArgSpec{{Name: "args", Optional: true, Rest: true}},
+            Run: func(c *Context) error {
+                return c.s.runExternal(path, c)
+            },
+        })
+        logrus.WithFields(logrus.Fields{"command": name, "path": path}).Debug("registered external command")
+    }
+}
+
+// runExternal executes an external command and applies its response.
+func (s *session) runExternal(path string, c *Context) error {
+    argv := c.Args.Words
+    cwd, _ := os.Getwd()
+    req := extRequest{
+        Command:        c.Name,
+        Args:           argv,
+        LastPrompt:     s.lastPrompt,
+        LastAnswer:     s.lastAnswer,
+        ConversationID: s.app.conversationID,
+        Model:          s.app.model,
+        Cwd:            cwd,
+        Attachments:    []extAttachment{},
+    }
+    if s.app.conversationID != "" {
+        req.ConversationURL = conversationURL(s.app.conversationID)
+    }
+    for _, at := range s.attachments {
+        req.Attachments = append(req.Attachments, extAttachment{Path: at.path, Content: at.content})
+    }
+    in, err := json.Marshal(req)
+    if err != nil {
+        return err
+    }
+
+    cmd := exec.Command(path, argv...)
+    cmd.Stdin = bytes.NewReader(in)
+    cmd.Stderr = os.Stderr
+    var out bytes.Buffer
+    cmd.Stdout = &out
+    logrus.WithFields(logrus.Fields{"command": c.Name, "path": path, "args": argv}).Info("running external command")
+    if err := cmd.Run(); err != nil {
+        if out.Len() > 0 {
+            fmt.Print(out.String())
+        }
+        return err
+    }
+
+    resp, ok := parseExtResponse(out.Bytes())
+    if !ok {
+        fmt.Print(out.String())
+        return nil
+    }
+    if resp.Print != "" {
+        fmt.Println(strings.TrimRight(resp.Print, "\n"))
+    }
+    for _, at := range resp.Attach {
+        content := at.Content
+        if content == "" {
+            // Paths without content are read through the provider so the
+            // MCP roots still apply.
+            var truncated bool
+            content, truncated, err = s.app.providerRead(at.Path, 0, 0)
+            if err != nil {
+                fmt.Printf("attach %s: %v\n", at.Path, err)
+                continue
+            }
+            if truncated {
+                fmt.Printf("Note: %s truncated.\n", at.Path)
+            }
+        }
+        c.Attach(at.Path, content)
+        fmt.Printf("Attached %s (%d chars).\n", at.Path, len(content))
+    }
+    if strings.TrimSpace(resp.Prompt) != "" {
+        c.Send(resp.Prompt)
+    }
+    return nil
+}
+
+// parseExtResponse decodes stdout as an extResponse if it is a JSON object
+// with at least one of its keys.
+func parseExtResponse(out []byte) (extResponse, bool) {
+    var resp extResponse
+    var keys map[string]json.RawMessage
+    trimmed := bytes.TrimSpace(out)
+    if !bytes.HasPrefix(trimmed, []byte("{")) || json.Unmarshal(trimmed, &keys) != nil {
+        return resp, false
+    }
+    _, p := keys["print"]
+    _, a := keys["attach"]
+    _, q := keys["prompt"]
+    if !p && !a && !q {
+        return resp, false
+    }
+    return resp, json.Unmarshal(trimmed, &resp) == nil
+}
@@ -0,0 +1,171 @@
+package app
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "time"
+
+    "github.com/chromedp/cdproto/runtime"
+    "github.com/chromedp/chromedp"
+    "github.com/sirupsen/logrus"
+)
+
+// loginTimeout bounds how long `chatbang login` waits for the user.
+const loginTimeout = 10 * time.Minute
+
+// loginPollInterval is how often the page is checked for login progress.
+const loginPollInterval = 2 * time.Second
+
+// ErrNotLoggedIn is returned by CheckLogin when the profile has no usable
+// ChatGPT session or clipboard permission.
+var ErrNotLoggedIn = errors.New("not logged in (run `chatbang login`)")
+
+// loginStateJS reports whether the logged-in composer is shown and whether
+// clipboard-read has been granted. Logged-out visitors also get a composer,
+// so the login/signup buttons must be absent too.
+const loginStateJS = `(async () => {
+    const composer = !!document.querySelector('#prompt-textarea');
+    const loginButton = !!document.querySelector('[data-testid="login-button"], [data-testid="signup-button"]');
+    let clipboard = 'unknown';
+    try {
+        clipboard = (await navigator.permissions.query({ name: 'clipboard-read' })).state;
+    } catch (e) {}
+    return { loggedIn: composer && !loginButton, clipboard: clipboard };
+})()`
+
+type loginState struct {
+    LoggedIn  bool   `json:"loggedIn"`
+    Clipboard string `json:"clipboard"`
+}
+
+func (s loginState) ready() bool { return s.LoggedIn && s.Clipboard == "granted" }
+
+func readLoginState(ctx context.Context) (loginState, error) {
+    var st loginState
+    pollCtx, cancel := context.WithTimeout(ctx, loginPollInterval*2)
+    defer cancel()
+    err := chromedp.Run(pollCtx, chromedp.Evaluate(loginStateJS, &st, func(p *runtime.EvaluateParams) *runtime.EvaluateParams { return p.WithAwaitPromise(true) }))
+    return st, err
+}
+
+// Login opens ChatGPT and prompts for clipboard permission within the profile.
+// It returns once the user is logged in and clipboard access is granted,
+// closing the browser automatically.
+func (a *App) Login() error {
+    // Logging in through someone else's window would be confusing; only a
+    // free (or force-unlocked) profile is accepted here.
+    if lock, err := readProfileLock(a.profileDir); err == nil && lock != nil && lock.alive() {
+        fmt.Printf("The browser profile %s is in use by %s; close it and retry.\n", a.profileDir, lock)
+        return ErrProfileLocked
+    }
+    if _, err := a.acquireProfile(); err != nil {
+        return err
+    }
+
+    allocatorCtx, cancel := chromedp.NewExecAllocator(context.Background(), a.allocatorOptions()...)
+    defer cancel()
+
+    ctx, cancel := chromedp.NewContext(allocatorCtx)
+    defer cancel()
+
+    logrus.Info("opening login/profile setup flow")
+    if err := chromedp.Run(ctx,
+        chromedp.Navigate(chatURL),
+        chromedp.Evaluate(`(async () => {
+            const permName = 'clipboard-read';
+            try {
+                const p = await navigator.permissions.query({ name: permName });
+                if (p.state !== 'granted') {
+                    alert("Please allow clipboard access in the popup that will appear now.");
+                }
+            } catch (e) {
+                try { await navigator.clipboard.readText(); } catch (_) {
+                    alert("Please allow clipboard access in the popup that will appear now.");
+                }
+            }
+        })();`, nil),
+        chromedp.Evaluate(`navigator.clipboard.readText().catch(() => {});`, nil),
+    ); err != nil {
+        return err
+    }
+
+    fmt.Println("Log in to ChatGPT in the browser window and allow clipboard access.")
+    deadline := time.After(loginTimeout)
+    ticker := time.NewTicker(loginPollInterval)
+    defer ticker.Stop()
+    var last loginState
+    for {
+        select {
+        case <-ticker.C:
+        case <-ctx.Done():
+            fmt.Println("Browser closed before login completed.")
+            return ErrNotLoggedIn
+        case <-deadline:
+            fmt.Printf("Timed out after %s waiting for login; run `chatbang login` again.\n", loginTimeout)
+            return ErrNotLoggedIn
+        }
+        st, err := readLoginState(ctx)
+        if err != nil {
+            // An open alert or permission prompt blocks evaluation; retry.
+            logrus.WithError(err).Debug("login state poll failed")
+            continue
+        }
+        if st.LoggedIn && !last.LoggedIn {
+            fmt.Println("Logged in.")
+        }
+        if st.Clipboard == "granted" && last.Clipboard != "granted" {
+            fmt.Println("Clipboard access granted.")
+        }
+        if st.LoggedIn && st.Clipboard != "granted" && st.Clipboard != last.Clipboard {
+            // Re-trigger the permission prompt after login navigations.
+            _ = chromedp.Run(ctx, chromedp.Evaluate(`navigator.clipboard.readText().catch(() => {});`, nil))
+        }
+        last = st
+        if st.ready() {
+            logrus.Info("login complete")
+            fmt.Println("Login complete; closing the browser.")
+            // Close gracefully so Chrome flushes cookies to the profile.
+            if err := chromedp.Cancel(ctx); err != nil {
+                logrus.WithError(err).Debug("closing browser")
+            }
+            return nil
+        }
+    }
+}
+
+// CheckLogin opens the profile in a headless browser and reports whether it
+// is logged in with clipboard access granted. It returns ErrNotLoggedIn if not.
+func (a *App) CheckLogin() error {
+    if lock, err := readProfileLock(a.profileDir); err == nil && lock != nil && lock.alive() {
+        return ErrProfileLocked
+    }
+    opts := append(a.allocatorOptions(), chromedp.Flag("headless", "new"))
+    allocatorCtx, cancel := chromedp.NewExecAllocator(context.Background(), opts...)
+    defer cancel()
+
+    ctx, cancel := chromedp.NewContext(allocatorCtx)
+    defer cancel()
+    ctx, cancel = context.WithTimeout(ctx, time.Minute)
+    defer cancel()
+
+    if err := chromedp.Run(ctx, chromedp.Navigate(chatURL), chromedp.WaitReady(`body`, chromedp.ByQuery)); err != nil {
+        return err
+    }
+    // The composer renders after hydration; give it a few polls.
+    var st loginState
+    for i := 0; i < 5; i++ {
+        var err error
+        st, err = readLoginState(ctx)
+        if err == nil && st.LoggedIn {
+            break
+        }
+        time.Sleep(loginPollInterval)
+    }
+    logrus.WithFields(logrus.Fields{"loggedIn": st.LoggedIn, "clipboard": st.Clipboard}).Info("login check")
+    fmt.Printf("Logged in: %t\nClipboard access: %s\n", st.LoggedIn, st.Clipboard)
+    if !st.ready() {
+        return ErrNotLoggedIn
+    }
+    return nil
+}
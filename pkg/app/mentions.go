@@ -0,0 +1,99 @@
+package app
+
+import (
+    "fmt"
+    "path/filepath"
+    "regexp"
+    "strings"
+
+    "github.com/sirupsen/logrus"
+)
+
+// mentionPattern finds @path mentions at the start of input or after
+// whitespace or an opening bracket, so e-mail addresses are left alone.
+var mentionPattern = regexp.MustCompile(`(?:^|[\s(\[])@([^\s@]+)`)
+
+// attachMentions attaches the files, line ranges (@file#L10-20), symbols
+// (@file::Name) and directory listings (@dir/) mentioned in line for the
+// next turn. The mentions stay in the prompt; ones that cannot be resolved
+// are reported and left as plain text.
+func (s *session) attachMentions(line string) {
+    seen := map[string]bool{}
+    for _, m := range mentionPattern.FindAllStringSubmatch(line, -1) {
+        token := strings.TrimRight(m[1], `.,;:!?)]'"`)
+        if token == "" || seen[token] {
+            continue
+        }
+        seen[token] = true
+        label, content, err := s.app.resolveMention(token)
+        if err != nil {
+            // Words like @Override or @team are not meant as paths.
+            if strings.ContainsAny(token, `/.\#`) || strings.Contains(token, "::") {
+                fmt.Printf("Warning: @%s left as text: %v\n", token, err)
+            }
+            logrus.WithFields(logrus.Fields{"mention": token}).WithError(err).Debug("unresolved mention")
+            continue
+        }
+        s.attach(label, content, func() (string, error) {
+            _, content, err := s.app.resolveMention(token)
+            return content, err
+        })
+        fmt.Printf("Attached %s (%d chars).\n", label, len(content))
+        logrus.WithFields(logrus.Fields{"mention": token, "chars": len(content)}).Info("attached mention")
+    }
+}
+
+// resolveMention reads a mentioned file, line range, symbol or directory
+// through the provider, so the MCP roots apply. It returns the attachment
+// label and content.
+func (a *App) resolveMention(spec string) (string, string, error) {
+    path, lines, symbol := splitScope(spec)
+    info, err := a.providerStat(path)
+    if err != nil {
+        return "", "", err
+    }
+    if dir, _ := info["dir"].(bool); dir {
+        if lines != "" || symbol != "" {
+            return "", "", fmt.Errorf("line ranges and symbols apply to files, not directories")
+        }
+        listing, err := a.dirListing(path)
+        if err != nil {
+            return "", "", err
+        }
+        return strings.TrimSuffix(path, "/") + "/ (listing)", listing, nil
+    }
+    label, content, truncated, err := a.readScoped(spec, 0)
+    if err != nil {
+        return "", "", err
+    }
+    if truncated {
+        fmt.Printf("Note: %s truncated.\n", path)
+    }
+    return label, content, nil
+}
+
+// dirListing renders the entries directly inside path, one per line, with
+// directories marked by a trailing slash.
+func (a *App) dirListing(path string) (string, error) {
+    entries, err := a.providerList(path, 1)
+    if err != nil {
+        return "", err
+    }
+    var b strings.Builder
+    for _, e := range entries {
+        p, _ := e["path"].(string)
+        if rel, err := filepath.Rel(".", p); err == nil {
+            p = rel
+        }
+        if dir, _ := e["dir"].(bool); dir {
+            fmt.Fprintf(&b, "%s/\n", p)
+            continue
+        }
+        size, _ := e["size"].(int64)
+        fmt.Fprintf(&b, "%s\t%d bytes\n", p, size)
+    }
+    if b.Len() == 0 {
+        return "(empty directory)\n", nil
+    }
+    return b.String(), nil
+}
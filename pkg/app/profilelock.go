@@ -0,0 +1,171 @@
+package app
+
+import (
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/sirupsen/logrus"
+)
+
+// ErrProfileLocked is returned when another Chrome owns the profile and the
+// user chose not to attach to it or wait for it.
+var ErrProfileLocked = errors.New("profile is in use by another browser")
+
+// Chrome's singleton files; SingletonLock is a symlink to "<host>-<pid>".
+var singletonFiles = []string{"SingletonLock", "SingletonSocket", "SingletonCookie"}
+
+// profileLock describes the owner of profile_data/SingletonLock.
+type profileLock struct {
+    host string
+    pid  int
+}
+
+// readProfileLock returns the current lock owner, or nil if the profile is free.
+func readProfileLock(profileDir string) (*profileLock, error) {
+    target, err := os.Readlink(filepath.Join(profileDir, "SingletonLock"))
+    if err != nil {
+        if errors.Is(err, os.ErrNotExist) {
+            return nil, nil
+        }
+        return nil, err
+    }
+    i := strings.LastIndex(target, "-")
+    if i <= 0 {
+        return nil, fmt.Errorf("unrecognized SingletonLock target %q", target)
+    }
+    pid, err := strconv.Atoi(target[i+1:])
+    if err != nil {
+        return nil, fmt.Errorf("unrecognized SingletonLock target %q", target)
+    }
+    return &profileLock{host: target[:i], pid: pid}, nil
+}
+
+// local reports whether the lock was taken on this machine.
+func (l *profileLock) local() bool {
+    host, err := os.Hostname()
+    return err == nil && host == l.host
+}
+
+// alive reports whether the owning process still runs. Locks from other hosts
+// can't be checked and are treated as alive.
+func (l *profileLock) alive() bool {
+    if !l.local() {
+        return true
+    }
+    return processAlive(l.pid)
+}
+
+func (l *profileLock) String() string {
+    return fmt.Sprintf("pid %d on %s", l.pid, l.host)
+}
+
+// devToolsURL reads the DevTools endpoint Chrome publishes in the profile
+// (DevToolsActivePort: port on the first line, browser path on the second).
+func devToolsURL(profileDir string) (string, error) {
+    data, err := os.ReadFile(filepath.Join(profileDir, "DevToolsActivePort"))
+    if err != nil {
+        return "", err
+    }
+    lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+    port, err := strconv.Atoi(strings.TrimSpace(lines[0]))
+    if err != nil || port <= 0 {
+        return "", fmt.Errorf("invalid DevToolsActivePort")
+    }
+    url := fmt.Sprintf("ws://127.0.0.1:%d", port)
+    if len(lines) > 1 {
+        url += strings.TrimSpace(lines[1])
+    }
+    return url, nil
+}
+
+// removeProfileLock deletes Chrome's singleton files. Only the lock files are
+// touched; profile data is never modified.
+func removeProfileLock(profileDir string) error {
+    for _, name := range singletonFiles {
+        if err := os.Remove(filepath.Join(profileDir, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+            return err
+        }
+    }
+    return nil
+}
+
+// acquireProfile makes sure chatbang can use the profile. It returns a DevTools
+// URL when the user chose to attach to the running owner, or "" when a fresh
+// browser should be launched.
+func (a *App) acquireProfile() (string, error) {
+    lock, err := readProfileLock(a.profileDir)
+    if err != nil {
+        return "", err
+    }
+    if lock == nil {
+        return "", nil
+    }
+    log := logrus.WithFields(logrus.Fields{"pid": lock.pid, "host": lock.host, "profileDir": a.profileDir})
+    if !lock.alive() {
+        if !a.Options.ForceUnlock {
+            fmt.Printf("The browser profile is locked by %s, which is no longer running.\n", lock)
+            fmt.Println("Re-run with --force-unlock to remove the stale lock.")
+            return "", ErrProfileLocked
+        }
+        log.Warn("removing stale profile lock")
+        if err := removeProfileLock(a.profileDir); err != nil {
+            return "", fmt.Errorf("remove stale lock: %w", err)
+        }
+        fmt.Printf("Removed stale profile lock (%s).\n", lock)
+        return "", nil
+    }
+    switch {
+    case a.Options.ForceUnlock && !lock.local():
+        // Its owner cannot be checked from here; removing the lock could
+        // let two browsers write to a shared profile.
+        fmt.Printf("Not unlocking: the profile is locked from another host (%s), whose browser may still be running.\n", lock.host)
+        log.Warn("refusing to remove profile lock from another host")
+    case a.Options.ForceUnlock:
+        fmt.Printf("Not unlocking: the profile is held by a running browser (%s).\n", lock)
+    }
+
+    log.Info("profile locked by running browser")
+    fmt.Printf("The browser profile %s is in use by %s.\n", a.profileDir, lock)
+    if !stdinIsTerminal() {
+        return "", ErrProfileLocked
+    }
+    for {
+        switch strings.ToLower(promptLine("[a]ttach to it, [w]ait for it to exit, or [q]uit? ")) {
+        case "a", "attach":
+            url, err := devToolsURL(a.profileDir)
+            if err != nil {
+                fmt.Println("That browser has no debugging port open; close it or choose wait.")
+                log.WithError(err).Warn("no DevTools endpoint for locked profile")
+                continue
+            }
+            log.WithField("url", url).Info("attaching to running browser")
+            return url, nil
+        case "w", "wait":
+            fmt.Println("Waiting for the other browser to exit (Ctrl-C to abort)...")
+            for {
+                time.Sleep(time.Second)
+                cur, err := readProfileLock(a.profileDir)
+                if err != nil {
+                    return "", err
+                }
+                if cur == nil || !cur.alive() {
+                    // The owner exited without cleaning up; its lock is stale.
+                    if cur != nil {
+                        if err := removeProfileLock(a.profileDir); err != nil {
+                            return "", err
+                        }
+                    }
+                    log.Info("profile lock released")
+                    return "", nil
+                }
+            }
+        case "q", "quit", "":
+            return "", ErrProfileLocked
+        }
+    }
+}
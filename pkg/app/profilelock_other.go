@@ -0,0 +1,9 @@
+//go:build !unix
+
+package app
+
+// processAlive cannot check processes here, so it treats every owner as
+// running; stale locks then have to be removed by hand.
+func processAlive(pid int) bool {
+    return true
+}
@@ -0,0 +1,122 @@
+package app
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+
+    "github.com/sirupsen/logrus"
+
+    "gg/internal/project"
+)
+
+func init() {
+    RegisterCommand(Command{
+        Name: "context",
+        Help: "show the project context file, or reload it",
+        Long: "Show the project context: the nearest .chatbang.md or CHATBANG.md at or above the working directory (up to the git root), its default attachments and whether it was sent. :context reload re-reads it, attaches its files again and sends it with the next prompt.",
+        Args: []ArgSpec{{Name: "reload", Optional: true, Values: []string{"reload"}}},
+        Run: func(c *Context) error {
+            switch c.Args.Arg(0) {
+            case "":
+                c.s.printContext()
+                return nil
+            case "reload":
+                found, err := c.s.loadContext()
+                if err != nil {
+                    return err
+                }
+                if !found {
+                    fmt.Printf("No %s found up to the git root.\n", strings.Join(project.Names, " or "))
+                }
+                return nil
+            }
+            return ErrUsage
+        },
+    })
+}
+
+// loadContext loads the project context file for the working directory, if
+// any, and attaches its default attachments. Its instructions are sent with
+// the next prompt. It reports whether a file was found; a file that cannot
+// be loaded clears the previous context and returns the error.
+func (s *session) loadContext() (bool, error) {
+    s.project = nil
+    cwd, _ := os.Getwd()
+    path, ok := project.Find(cwd)
+    if !ok {
+        return false, nil
+    }
+    ctx, err := project.Load(path)
+    if err != nil {
+        logrus.WithError(err).WithField("path", path).Warn("failed to load project context")
+        return false, err
+    }
+    s.project, s.projectSent = &ctx, false
+    fmt.Printf("Project context: %s\n", displayPath(cwd, path))
+    for _, spec := range ctx.Attach {
+        words := strings.Fields(spec)
+        for i, w := range words {
+            p, exclude := strings.CutPrefix(w, "!")
+            if filepath.IsAbs(p) || (exclude && !strings.Contains(p, "/")) {
+                continue
+            }
+            p = displayPath(cwd, filepath.Join(ctx.Dir(), p))
+            if exclude {
+                p = "!" + p
+            }
+            words[i] = p
+        }
+        if err := s.attachPaths(words, 0, defaultAttachBudget); err != nil {
+            fmt.Printf("Warning: project context: attach %s: %v\n", spec, err)
+        }
+    }
+    logrus.WithFields(logrus.Fields{"path": path, "chars": len(ctx.Body), "attach": len(ctx.Attach)}).Info("loaded project context")
+    return true, nil
+}
+
+// displayPath returns path relative to cwd when it is below it, else
+// unchanged.
+func displayPath(cwd, path string) string {
+    rel, err := filepath.Rel(cwd, path)
+    if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+        return path
+    }
+    return rel
+}
+
+// contextPending reports whether the project context goes with the next
+// prompt.
+func (s *session) contextPending() bool {
+    return s.project != nil && !s.projectSent && s.project.Body != ""
+}
+
+func (s *session) writeContext(b *strings.Builder) {
+    fmt.Fprintf(b, "Project context (%s):\n\n", filepath.Base(s.project.Path))
+    b.WriteString(s.project.Body)
+    b.WriteString("\n\n")
+}
+
+// printContext shows the project context for :context.
+func (s *session) printContext() {
+    if s.project == nil {
+        if s.app.Options.NoContext {
+            fmt.Println("No project context (skipped with --no-context; :context reload loads it).")
+        } else {
+            fmt.Printf("No project context. Add %s to the repository.\n", strings.Join(project.Names, " or "))
+        }
+        return
+    }
+    state := "sent"
+    if !s.projectSent {
+        state = "pending"
+    }
+    fmt.Printf("Project context: %s (%s)\n", s.project.Path, state)
+    for _, spec := range s.project.Attach {
+        fmt.Printf("  attach: %s\n", spec)
+    }
+    if s.project.Body != "" {
+        fmt.Printf("\n%s\n", s.project.Body)
+    }
+}
@@ -0,0 +1,58 @@
+package app
+
+import (
+    "fmt"
+    "os"
+    "strings"
+)
+
+// promptLine prints msg and reads one line from stdin. It reads byte by byte
+// so nothing beyond the newline is consumed from the REPL's input.
+func promptLine(msg string) string {
+    fmt.Print(msg)
+    var b strings.Builder
+    buf := make([]byte, 1)
+    for {
+        n, err := os.Stdin.Read(buf)
+        if n == 0 || err != nil || buf[0] == '\n' {
+            break
+        }
+        b.WriteByte(buf[0])
+    }
+    return strings.TrimSpace(b.String())
+}
+
+func stdinIsTerminal() bool {
+    fi, err := os.Stdin.Stat()
+    return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+func stdoutIsTerminal() bool {
+    fi, err := os.Stdout.Stat()
+    return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// confirm asks a yes/no question through the session's line editor, so it
+// shares the REPL's input. Anything but y/yes counts as no.
+func (s *session) confirm(msg string) bool {
+    answer, err := s.editor.ReadLine(msg + " [y/N] ")
+    if err != nil {
+        return false
+    }
+    switch strings.ToLower(strings.TrimSpace(answer)) {
+    case "y", "yes":
+        return true
+    }
+    return false
+}
+
+// choose asks a question answered with one letter and returns it lowercased,
+// or 'n' for an empty answer or read error.
+func (s *session) choose(msg string) byte {
+    answer, err := s.editor.ReadLine(msg + " ")
+    answer = strings.ToLower(strings.TrimSpace(answer))
+    if err != nil || answer == "" {
+        return 'n'
+    }
+    return answer[0]
+}
@@ -0,0 +1,251 @@
+package app
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "io"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "strings"
+    "time"
+
+    "github.com/sirupsen/logrus"
+
+    "gg/internal/codeblock"
+)
+
+const (
+    defaultRunTimeout = 30 * time.Second
+    defaultRunOutput  = 64 * 1024
+)
+
+// runResult is the outcome of :run, kept for :feedback.
+type runResult struct {
+    block    int
+    lang     string
+    command  string
+    stdout   string
+    stderr   string
+    exitCode int
+    timedOut bool
+    timeout  time.Duration
+    elapsed  time.Duration
+}
+
+// runner describes how to execute a block of one language.
+type runner struct {
+    file string
+    cmd  func(file string) []string
+}
+
+var runners = map[string]runner{
+    "sh":     {"script.sh", func(f string) []string { return []string{"sh", f} }},
+    "shell":  {"script.sh", func(f string) []string { return []string{"sh", f} }},
+    "bash":   {"script.sh", func(f string) []string { return []string{"bash", f} }},
+    "zsh":    {"script.sh", func(f string) []string { return []string{"zsh", f} }},
+    "python": {"main.py", func(f string) []string { return []string{pythonBinary(), f} }},
+    "py":     {"main.py", func(f string) []string { return []string{pythonBinary(), f} }},
+    "go":     {"main.go", func(f string) []string { return []string{"go", "run", f} }},
+    "golang": {"main.go", func(f string) []string { return []string{"go", "run", f} }},
+}
+
+func pythonBinary() string {
+    if _, err := exec.LookPath("python3"); err == nil {
+        return "python3"
+    }
+    return "python"
+}
+
+func init() {
+    RegisterCommand(Command{
+        Name: "run",
+        Help: "run shell, Python or Go code block N in a temp directory",
+        Long: "Run code block N of the last answer in a fresh temporary directory, after showing the command and asking for confirmation. Output is shown as it arrives and captured (up to the output cap); the process is killed when the timeout expires. Use :feedback to send the result back as the next prompt.",
+        Args: []ArgSpec{{Name: "N"}},
+        Options: []OptionSpec{
+            {Name: "timeout", Value: "DURATION", Help: "kill the process after this long (default 30s)"},
+            {Name: "cap", Value: "BYTES", Help: "capture at most this much of stdout and of stderr (default 65536)"},
+        },
+        Run: func(c *Context) error {
+            b, err := c.s.block(c.Args.Arg(0))
+            if err != nil {
+                return err
+            }
+            timeout := defaultRunTimeout
+            if v, ok := c.Args.Option("timeout"); ok {
+                if timeout, err = time.ParseDuration(v); err != nil || timeout <= 0 {
+                    return fmt.Errorf("timeout must be a duration such as 10s or 2m")
+                }
+            }
+            limit, err := c.Args.Int("cap", defaultRunOutput)
+            if err != nil {
+                return err
+            }
+            res, err := c.s.runBlock(c.Args.Arg(0), b, timeout, limit)
+            if err != nil || res == nil {
+                return err
+            }
+            c.s.lastRun = res
+            fmt.Println(res.summary())
+            fmt.Println("Use :feedback [note] to send this result as the next prompt.")
+            return nil
+        },
+    })
+    RegisterCommand(Command{
+        Name: "feedback",
+        Help: "send the output of the last :run as the next prompt",
+        Args: []ArgSpec{{Name: "note", Optional: true, Rest: true}},
+        Run: func(c *Context) error {
+            if c.s.lastRun == nil {
+                return fmt.Errorf("nothing to send; use :run N first")
+            }
+            prompt := c.s.lastRun.feedback(c.Args.Arg(0))
+            c.s.lastRun = nil
+            c.Send(prompt)
+            return nil
+        },
+    })
+}
+
+// runBlock executes b after confirmation. It returns nil if the user declined.
+func (s *session) runBlock(n string, b codeblock.Block, timeout time.Duration, limit int) (*runResult, error) {
+    r, ok := runners[b.Lang]
+    if !ok {
+        return nil, fmt.Errorf("cannot run %q blocks (supported: sh, bash, zsh, python, go)", b.Lang)
+    }
+    dir, err := os.MkdirTemp("", "chatbang-run-*")
+    if err != nil {
+        return nil, err
+    }
+    defer os.RemoveAll(dir)
+    file := filepath.Join(dir, r.file)
+    if err := os.WriteFile(file, []byte(b.Code), 0o600); err != nil {
+        return nil, err
+    }
+    argv := r.cmd(r.file)
+    command := strings.Join(argv, " ")
+
+    fmt.Printf("Block %s (%s, %d lines):\n", n, b.Lang, b.Lines())
+    fmt.Print(b.Code)
+    fmt.Printf("Command: %s\nDirectory: %s (removed afterwards)\nTimeout: %s\n", command, dir, timeout)
+    if !s.confirm("Run it?") {
+        fmt.Println("Not run.")
+        return nil, nil
+    }
+
+    logrus.WithFields(logrus.Fields{"block": n, "lang": b.Lang, "command": command, "dir": dir, "timeout": timeout}).Info(":run")
+    res, err := execCapture(argv, dir, timeout, limit, true)
+    if err != nil {
+        return nil, err
+    }
+    res.lang = b.Lang
+    fmt.Sscan(n, &res.block)
+    return res, nil
+}
+
+// execCapture runs argv in dir, capturing up to limit bytes of stdout and of
+// stderr (echoed to the terminal if echo is set), and kills it after timeout.
+func execCapture(argv []string, dir string, timeout time.Duration, limit int, echo bool) (*runResult, error) {
+    ctx, cancel := context.WithTimeout(context.Background(), timeout)
+    defer cancel()
+    cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+    cmd.Dir = dir
+    cmd.WaitDelay = 2 * time.Second
+    stdout := &cappedWriter{limit: limit}
+    stderr := &cappedWriter{limit: limit}
+    if echo {
+        stdout.echo, stderr.echo = os.Stdout, os.Stderr
+    }
+    cmd.Stdout, cmd.Stderr = stdout, stderr
+
+    start := time.Now()
+    err := cmd.Run()
+    res := &runResult{
+        command:  strings.Join(argv, " "),
+        stdout:   stdout.String(),
+        stderr:   stderr.String(),
+        elapsed:  time.Since(start).Round(time.Millisecond),
+        timedOut: errors.Is(ctx.Err(), context.DeadlineExceeded),
+        timeout:  timeout,
+    }
+    var exitErr *exec.ExitError
+    switch {
+    case err == nil:
+    case errors.As(err, &exitErr):
+        res.exitCode = exitErr.ExitCode()
+    case res.timedOut:
+        res.exitCode = -1
+    default:
+        return nil, err
+    }
+    return res, nil
+}
+
+func (r *runResult) summary() string {
+    if r.timedOut {
+        return fmt.Sprintf("[killed after the %s timeout]", r.timeout)
+    }
+    return fmt.Sprintf("[exit code %d, %s]", r.exitCode, r.elapsed)
+}
+
+// feedback renders the result as a prompt, followed by the user's note.
+func (r *runResult) feedback(note string) string {
+    var b strings.Builder
+    fmt.Fprintf(&b, "I ran code block %d (%s) from your last answer with `%s`. ", r.block, r.lang, r.command)
+    if r.timedOut {
+        fmt.Fprintf(&b, "It was killed after the %s timeout.\n\n", r.timeout)
+    } else {
+        fmt.Fprintf(&b, "Exit code: %d.\n\n", r.exitCode)
+    }
+    for _, out := range []struct{ name, text string }{{"stdout", r.stdout}, {"stderr", r.stderr}} {
+        if strings.TrimSpace(out.text) == "" {
+            fmt.Fprintf(&b, "%s: (empty)\n\n", out.name)
+            continue
+        }
+        fmt.Fprintf(&b, "%s:\n```\n%s\n```\n\n", out.name, strings.TrimRight(out.text, "\n"))
+    }
+    switch note = strings.TrimSpace(note); {
+    case note != "":
+        b.WriteString(note)
+    case r.timedOut || r.exitCode != 0:
+        b.WriteString("Please fix the code based on this output.")
+    }
+    return strings.TrimRight(b.String(), "\n")
+}
+
+// cappedWriter keeps (and echoes, if echo is set) the first limit bytes
+// written to it and counts the rest.
+type cappedWriter struct {
+    limit   int
+    echo    io.Writer
+    buf     strings.Builder
+    dropped int
+}
+
+func (w *cappedWriter) Write(p []byte) (int, error) {
+    keep := p
+    if room := w.limit - w.buf.Len(); len(keep) > room {
+        if room < 0 {
+            room = 0
+        }
+        if w.dropped == 0 && w.echo != nil {
+            defer fmt.Fprintln(w.echo, "\n[output cap reached; further output is discarded]")
+        }
+        w.dropped += len(keep) - room
+        keep = keep[:room]
+    }
+    w.buf.Write(keep)
+    if w.echo != nil {
+        w.echo.Write(keep)
+    }
+    return len(p), nil
+}
+
+func (w *cappedWriter) String() string {
+    if w.dropped > 0 {
+        return w.buf.String() + fmt.Sprintf("\n[... %d more bytes truncated]", w.dropped)
+    }
+    return w.buf.String()
+}
@@ -0,0 +1,110 @@
+package app
+
+import (
+    "fmt"
+    "regexp"
+    "strconv"
+    "strings"
+
+    "gg/internal/outline"
+)
+
+var lineRangePattern = regexp.MustCompile(`^L(\d+)(?:-L?(\d+))?$`)
+
+// splitScope splits "path#L10-20" into path and line range, and
+// "path::Name" into path and symbol.
+func splitScope(spec string) (path, lines, symbol string) {
+    if i := strings.LastIndex(spec, "#"); i > 0 && lineRangePattern.MatchString(spec[i+1:]) {
+        return spec[:i], spec[i+1:], ""
+    }
+    if i := strings.LastIndex(spec, "::"); i > 0 && i+2 < len(spec) {
+        return spec[:i], "", spec[i+2:]
+    }
+    return spec, "", ""
+}
+
+// readScoped reads a file, or the part of it named by a #L10-20 or ::Name
+// suffix, through the provider. Parts are numbered by line and labelled
+// with their range, so answers can refer to exact lines. limit applies to
+// whole files only.
+func (a *App) readScoped(spec string, limit int) (label, content string, truncated bool, err error) {
+    path, lines, symbol := splitScope(spec)
+    if lines == "" && symbol == "" {
+        content, truncated, err = a.providerRead(path, 0, limit)
+        return path, content, truncated, err
+    }
+    content, truncated, err = a.providerRead(path, 0, 0)
+    if err != nil {
+        return "", "", false, err
+    }
+    var from, to int
+    if lines != "" {
+        from, to, err = parseLineRange(lines)
+    } else {
+        var r outline.Range
+        r, err = outline.Find(path, content, symbol)
+        from, to = r.Start, r.End
+    }
+    if err == nil {
+        var snippet string
+        snippet, to, err = sliceLines(content, from, to)
+        content = numberLines(snippet, from)
+    }
+    if err != nil {
+        if truncated {
+            err = fmt.Errorf("%w (file truncated at max_bytes)", err)
+        }
+        return "", "", false, err
+    }
+    span := fmt.Sprintf("L%d-%d", from, to)
+    if from == to {
+        span = fmt.Sprintf("L%d", from)
+    }
+    label = path + "#" + span
+    if symbol != "" {
+        label = fmt.Sprintf("%s::%s (%s)", path, symbol, span)
+    }
+    return label, content, false, nil
+}
+
+// parseLineRange parses "L10-20", "L10-L20" or "L10".
+func parseLineRange(s string) (from, to int, err error) {
+    m := lineRangePattern.FindStringSubmatch(s)
+    if m == nil {
+        return 0, 0, fmt.Errorf("bad line range %q (use L10-20)", s)
+    }
+    from, _ = strconv.Atoi(m[1])
+    to = from
+    if m[2] != "" {
+        to, _ = strconv.Atoi(m[2])
+    }
+    if from < 1 || to < from {
+        return 0, 0, fmt.Errorf("bad line range %q", s)
+    }
+    return from, to, nil
+}
+
+// sliceLines returns lines from..to (1-based, inclusive) of content. to is
+// clamped to the last line and returned.
+func sliceLines(content string, from, to int) (string, int, error) {
+    lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+    if from > len(lines) {
+        return "", 0, fmt.Errorf("line %d is past the end (%d lines)", from, len(lines))
+    }
+    if to > len(lines) {
+        to = len(lines)
+    }
+    return strings.Join(lines[from-1:to], "\n") + "\n", to, nil
+}
+
+// numberLines prefixes each line of snippet with its line number, starting
+// at first.
+func numberLines(snippet string, first int) string {
+    lines := strings.Split(strings.TrimSuffix(snippet, "\n"), "\n")
+    width := len(strconv.Itoa(first + len(lines) - 1))
+    var b strings.Builder
+    for i, l := range lines {
+        fmt.Fprintf(&b, "%*d| %s\n", width, first+i, l)
+    }
+    return b.String()
+}
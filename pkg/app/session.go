@@ -0,0 +1,342 @@
+package app
+
+import (
+    "errors"
+    "fmt"
+    "io"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "strings"
+    "time"
+
+    markdown "github.com/MichaelMure/go-term-markdown"
+    "github.com/sirupsen/logrus"
+
+    "gg/internal/lineedit"
+    "gg/internal/project"
+    "gg/internal/sessions"
+    "gg/internal/tokens"
+)
+
+// session is the REPL state machine. It owns input, local ":" commands,
+// attachments and turns for the whole conversation, so commands work before
+// and after the first message alike.
+type session struct {
+    app    *App
+    editor *lineedit.Editor
+
+    attachments []attachment
+    style       responseStyle
+    turns       int
+    lastPrompt  string
+    lastAnswer  string
+
+    // log holds this session's turns for :export; transcript is nil when
+    // save_sessions=false or the store is unusable.
+    log        []sessions.Turn
+    transcript *sessions.Store
+    id         string
+
+    // applied is the stack of :apply changes for :undo-apply.
+    applied [][]appliedFile
+    // lastRun is the result of the last :run, for :feedback.
+    lastRun *runResult
+    // project is the project context file, sent with the first prompt
+    // (projectSent) and again after :context reload.
+    project     *project.Context
+    projectSent bool
+}
+
+func (a *App) newSession(in *os.File) *session {
+    hist, err := lineedit.LoadHistory(filepath.Join(a.configDir, "history"), a.historySize)
+    if err != nil {
+        logrus.WithError(err).Warn("failed to load history")
+    }
+    a.registerExternalCommands()
+    s := &session{app: a, editor: lineedit.New(in, os.Stdout, hist)}
+    s.editor.Complete = s.complete
+    s.id = sessions.NewID(time.Now())
+    if a.saveSessions {
+        store, err := sessions.Open(filepath.Join(a.configDir, "sessions"))
+        if err != nil {
+            logrus.WithError(err).Warn("session transcripts disabled")
+        } else {
+            s.transcript = store
+        }
+    }
+    return s
+}
+
+// run handles first (if any) as the opening input, then reads lines until
+// EOF or Ctrl-D.
+func (s *session) run(first string) error {
+    if strings.TrimSpace(first) != "" {
+        s.handle(first)
+    }
+    for {
+        line, err := s.readInput()
+        switch {
+        case errors.Is(err, lineedit.ErrInterrupted):
+            continue
+        case errors.Is(err, io.EOF):
+            return nil
+        case err != nil:
+            return err
+        }
+        if strings.TrimSpace(line) != "" {
+            if err := s.editor.AddHistory(line); err != nil {
+                logrus.WithError(err).Warn("failed to save history")
+            }
+        }
+        s.handle(line)
+    }
+}
+
+// fence opens and closes multi-line input in the REPL.
+const fence = `"""`
+
+// readInput reads one logical input. A line starting with """ opens a fenced
+// block that continues until a line ending with """; the lines in between are
+// sent as one multi-line prompt.
+func (s *session) readInput() (string, error) {
+    line, err := s.editor.ReadLine(s.promptString())
+    if err != nil || !strings.HasPrefix(strings.TrimSpace(line), fence) {
+        return line, err
+    }
+    first := strings.TrimPrefix(strings.TrimSpace(line), fence)
+    if len(first) >= len(fence) && strings.HasSuffix(first, fence) {
+        return strings.TrimSuffix(first, fence), nil
+    }
+    var lines []string
+    if first != "" {
+        lines = append(lines, first)
+    }
+    for {
+        next, err := s.editor.ReadLine("... ")
+        if errors.Is(err, lineedit.ErrInterrupted) {
+            return "", err
+        }
+        if err != nil && !errors.Is(err, io.EOF) {
+            return "", err
+        }
+        if errors.Is(err, io.EOF) || strings.HasSuffix(strings.TrimRight(next, " \t"), fence) {
+            lines = append(lines, strings.TrimSuffix(strings.TrimRight(next, " \t"), fence))
+            break
+        }
+        lines = append(lines, next)
+    }
+    return strings.TrimRight(strings.Join(lines, "\n"), "\n"), nil
+}
+
+// promptString renders the input prompt with a short state summary,
+// e.g. "[gpt-4o, 2 attached, ~3.1k tokens] > ".
+func (s *session) promptString() string {
+    var parts []string
+    if s.app.model != "" {
+        parts = append(parts, s.app.model)
+    }
+    if n := len(s.attachments); n > 0 {
+        parts = append(parts, fmt.Sprintf("%d attached", n))
+    }
+    if pending := s.pendingAttachments(); len(pending) > 0 {
+        parts = append(parts, "~"+formatTokens(tokens.Count(s.composeWith(pending, "")))+" tokens")
+    }
+    if len(parts) == 0 {
+        return "> "
+    }
+    return "[" + strings.Join(parts, ", ") + "] > "
+}
+
+// handle dispatches one input: a local command or a chat turn. Multi-line
+// input is never treated as a command.
+func (s *session) handle(line string) {
+    line = strings.TrimSpace(line)
+    if strings.Contains(line, "\n") {
+        s.userTurn(line)
+        return
+    }
+    switch {
+    case line == "":
+    case strings.HasPrefix(line, ":"):
+        if !s.handleLocalCommand(line) {
+            fmt.Println("Unknown command. Try :help")
+        }
+    default:
+        s.userTurn(line)
+    }
+}
+
+// userTurn sends a prompt the user wrote, after attaching its @mentions and
+// substituting the output of its !`cmd`s.
+func (s *session) userTurn(line string) {
+    s.attachMentions(line)
+    s.turn(s.substituteCommands(line))
+}
+
+// turn sends one prompt, with any pending attachments, and prints the answer.
+func (s *session) turn(line string) {
+    s.refreshPinned()
+    prompt := s.compose(line)
+    sent := s.pendingAttachments()
+    count, limit := tokens.Count(prompt), s.app.promptLimit()
+    if count > limit {
+        var ok bool
+        if prompt, ok = s.fitPrompt(line, count, limit); !ok {
+            return
+        }
+        count = tokens.Count(prompt)
+    }
+    fmt.Printf("[Thinking...]\n\n")
+    answer, err := s.app.ask(prompt)
+    if err != nil {
+        fmt.Printf("chat error: %v\n", err)
+        return
+    }
+    for i := range s.attachments {
+        s.attachments[i].sent = true
+    }
+    s.projectSent = true
+    s.turns++
+    s.lastPrompt = line
+    s.lastAnswer = answer
+    logrus.WithFields(logrus.Fields{"turn": s.turns, "chars": len(answer), "prompt_tokens": count, "answer_tokens": tokens.Count(answer), "limit": limit, "attachments": len(sent)}).Info("turn complete")
+    s.record(line, answer, sent)
+    fmt.Println(string(markdown.Render(answer, 80, 2)))
+}
+
+// pendingAttachments returns the attachments the next turn will send.
+func (s *session) pendingAttachments() []attachment {
+    var out []attachment
+    for _, at := range s.attachments {
+        if !at.sent {
+            out = append(out, at)
+        }
+    }
+    return out
+}
+
+// record appends the turn to the session log and transcript.
+func (s *session) record(prompt, answer string, sent []attachment) {
+    t := sessions.Turn{Time: time.Now(), Prompt: prompt, Answer: answer, Model: s.app.model}
+    if s.app.conversationID != "" {
+        t.ConversationURL = conversationURL(s.app.conversationID)
+    }
+    for _, at := range sent {
+        t.Attachments = append(t.Attachments, sessions.Attachment{Path: at.path, SHA256: sessions.Hash(at.content)})
+    }
+    s.log = append(s.log, t)
+    if s.transcript == nil {
+        return
+    }
+    if err := s.transcript.Append(s.id, t); err != nil {
+        logrus.WithError(err).WithField("session", s.id).Warn("failed to save transcript")
+    }
+}
+
+// editPrompt opens $VISUAL/$EDITOR on a temp file pre-filled with the last
+// prompt and returns what was saved.
+func (s *session) editPrompt() (string, error) {
+    editor := os.Getenv("VISUAL")
+    if editor == "" {
+        editor = os.Getenv("EDITOR")
+    }
+    if editor == "" {
+        editor = "vi"
+    }
+    f, err := os.CreateTemp("", "chatbang-*.md")
+    if err != nil {
+        return "", err
+    }
+    path := f.Name()
+    defer os.Remove(path)
+    _, werr := f.WriteString(s.lastPrompt)
+    if cerr := f.Close(); werr == nil {
+        werr = cerr
+    }
+    if werr != nil {
+        return "", werr
+    }
+
+    args := strings.Fields(editor)
+    cmd := exec.Command(args[0], append(args[1:], path)...)
+    cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+    logrus.WithFields(logrus.Fields{"editor": editor, "file": path}).Info(":edit")
+    if err := cmd.Run(); err != nil {
+        return "", fmt.Errorf("%s: %w", args[0], err)
+    }
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return "", err
+    }
+    return strings.TrimSpace(string(data)), nil
+}
+
+// compose builds the text typed into ChatGPT: unsent attachments first, then
+// the user's line.
+func (s *session) compose(line string) string {
+    return s.composeWith(s.pendingAttachments(), line)
+}
+
+// composeWith builds a prompt from atts and line, after the project context
+// if it was not sent yet.
+func (s *session) composeWith(atts []attachment, line string) string {
+    var b strings.Builder
+    if s.contextPending() {
+        s.writeContext(&b)
+    }
+    if len(atts) > 0 {
+        b.WriteString(s.attachHeader())
+        b.WriteString("\n\n")
+        for _, at := range atts {
+            writeAttachment(&b, at)
+        }
+    }
+    b.WriteString(line)
+    b.WriteString(s.style.suffix())
+    return b.String()
+}
+
+func (s *session) attachHeader() string {
+    if s.app.attachHeader == "" {
+        return defaultAttachHeader
+    }
+    return s.app.attachHeader
+}
+
+func writeAttachment(b *strings.Builder, at attachment) {
+    b.WriteString("File: ")
+    b.WriteString(at.path)
+    b.WriteString("\n````\n")
+    b.WriteString(at.content)
+    b.WriteString("\n````\n\n")
+}
+
+// printStatus shows the session state for :status.
+func (s *session) printStatus() {
+    pending := 0
+    for _, at := range s.attachments {
+        if !at.sent {
+            pending++
+        }
+    }
+    conv := s.app.conversationID
+    if conv == "" {
+        conv = "(new)"
+    }
+    model := s.app.model
+    if model == "" {
+        model = "(unknown)"
+    }
+    fmt.Printf("Model: %s\nStyle: %s\nTurns: %d\nAttachments: %d (%d pending)\nConversation: %s\n", model, s.style, s.turns, len(s.attachments), pending, conv)
+    if s.project != nil {
+        state := "sent"
+        if !s.projectSent {
+            state = "pending"
+        }
+        fmt.Printf("Context: %s (%s)\n", s.project.Path, state)
+    }
+    if s.transcript != nil {
+        fmt.Printf("Session: %s\n", s.id)
+    }
+}
@@ -0,0 +1,88 @@
+package app
+
+import (
+    "fmt"
+    "os"
+    "regexp"
+    "strings"
+
+    "github.com/sirupsen/logrus"
+)
+
+// substitutionPattern matches !`cmd` in a prompt.
+var substitutionPattern = regexp.MustCompile("!`([^`\n]+)`")
+
+// shellMeta are the characters that let a command do more than its first
+// words say; commands containing them always need confirmation.
+const shellMeta = ";&|<>$`()\\\n"
+
+// substituteCommands replaces each !`cmd` in line with the command's output,
+// exit code included, as a fenced block. Commands run in the working
+// directory after confirmation, unless shell_allow permits them. Declined or
+// failed commands are left as text.
+func (s *session) substituteCommands(line string) string {
+    return substitutionPattern.ReplaceAllStringFunc(line, func(m string) string {
+        command := strings.TrimSpace(substitutionPattern.FindStringSubmatch(m)[1])
+        if command == "" {
+            return m
+        }
+        if !s.app.shellAllowed(command) && !s.confirm(fmt.Sprintf("Run `%s` and include its output?", command)) {
+            fmt.Println("Not run; left as text.")
+            return m
+        }
+        cwd, _ := os.Getwd()
+        res, err := execCapture([]string{"sh", "-c", command}, cwd, defaultRunTimeout, defaultRunOutput, false)
+        if err != nil {
+            fmt.Printf("Warning: `%s` failed to start: %v\n", command, err)
+            return m
+        }
+        res.command = command
+        fmt.Printf("Ran `%s` %s\n", command, res.summary())
+        logrus.WithFields(logrus.Fields{"command": command, "exit": res.exitCode, "timedOut": res.timedOut, "stdout": len(res.stdout), "stderr": len(res.stderr)}).Info("shell substitution")
+        // The fence must start on a line of its own.
+        return "\n\n" + res.embed() + "\n"
+    })
+}
+
+// shellAllowed reports whether command may run without confirmation: its
+// leading words must match an entry of shell_allow, and it must not chain
+// or redirect commands.
+func (a *App) shellAllowed(command string) bool {
+    if strings.ContainsAny(command, shellMeta) {
+        return false
+    }
+    words := strings.Fields(command)
+    for _, entry := range a.shellAllow {
+        allowed := strings.Fields(entry)
+        if len(allowed) == 0 || len(allowed) > len(words) {
+            continue
+        }
+        if strings.Join(words[:len(allowed)], " ") == strings.Join(allowed, " ") {
+            return true
+        }
+    }
+    return false
+}
+
+// embed renders the result for inclusion in a prompt.
+func (r *runResult) embed() string {
+    var b strings.Builder
+    if r.timedOut {
+        fmt.Fprintf(&b, "Output of `%s` (killed after the %s timeout):\n", r.command, r.timeout)
+    } else {
+        fmt.Fprintf(&b, "Output of `%s` (exit code %d):\n", r.command, r.exitCode)
+    }
+    out := strings.TrimRight(r.stdout, "\n")
+    if errOut := strings.TrimRight(r.stderr, "\n"); errOut != "" {
+        if out != "" {
+            out += "\n"
+        }
+        out += "[stderr]\n" + errOut
+    }
+    fence := "```"
+    for strings.Contains(out, fence) {
+        fence += "`"
+    }
+    fmt.Fprintf(&b, "%s\n%s\n%s\n", fence, out, fence)
+    return b.String()
+}
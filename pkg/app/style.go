@@ -0,0 +1,57 @@
+package app
+
+import (
+    "fmt"
+    "strings"
+)
+
+// responseStyle is an instruction appended to every prompt to shape answers.
+type responseStyle struct {
+    name string // preset name, "custom" or "none"
+    text string // instruction sent to ChatGPT; empty for none
+}
+
+// stylePresets are the built-in styles selectable by name.
+var stylePresets = map[string]string{
+    "concise":   "Answer concisely, in at most a few lines, unless code is needed.",
+    "detailed":  "Give a thorough answer with explanations and examples where useful.",
+    "code-only": "Reply with code only, in fenced code blocks with language tags, without explanation.",
+}
+
+// parseStyle accepts a preset name, "none", or "custom:<text>".
+func parseStyle(spec string) (responseStyle, error) {
+    spec = strings.TrimSpace(spec)
+    lower := strings.ToLower(spec)
+    switch {
+    case lower == "" || lower == "none" || lower == "default":
+        return responseStyle{name: "none"}, nil
+    case strings.HasPrefix(lower, "custom:"):
+        text := strings.TrimSpace(spec[len("custom:"):])
+        if text == "" {
+            return responseStyle{}, fmt.Errorf("custom style needs text, e.g. custom:Answer in French")
+        }
+        return responseStyle{name: "custom", text: text}, nil
+    }
+    if text, ok := stylePresets[lower]; ok {
+        return responseStyle{name: lower, text: text}, nil
+    }
+    return responseStyle{}, fmt.Errorf("unknown style %q (use concise, detailed, code-only, none or custom:<text>)", spec)
+}
+
+// suffix returns what is appended to a prompt for this style.
+func (st responseStyle) suffix() string {
+    if st.text == "" {
+        return ""
+    }
+    return " (" + st.text + ")"
+}
+
+func (st responseStyle) String() string {
+    if st.name == "custom" {
+        return fmt.Sprintf("custom (%q)", st.text)
+    }
+    if st.name == "" {
+        return "none"
+    }
+    return st.name
+}
@@ -0,0 +1,103 @@
+package app
+
+import (
+    "fmt"
+    "os"
+    "strings"
+
+    "github.com/sirupsen/logrus"
+
+    "gg/internal/templates"
+)
+
+func init() {
+    RegisterCommand(Command{
+        Name:    "template",
+        Aliases: []string{"t"},
+        Help:    "send a prompt template, or list templates",
+        Long:    "Render a prompt template with name=value variables, attach the files it names and send it. Templates are Markdown files in .chatbang/templates (nearest to the working directory) and ~/.config/chatbang/templates. Without a name, lists the available templates and their variables.",
+        Args:    []ArgSpec{{Name: "name", Optional: true}, {Name: "var=value", Optional: true, Rest: true}},
+        Run: func(c *Context) error {
+            if len(c.Args.Words) == 0 {
+                return c.s.app.printTemplates()
+            }
+            vars, err := templates.ParseVars(c.Args.Words[1:])
+            if err != nil {
+                return err
+            }
+            prompt, err := c.s.useTemplate(c.Args.Words[0], vars)
+            if err != nil {
+                return err
+            }
+            c.Send(prompt)
+            return nil
+        },
+    })
+}
+
+// templateDirs returns the template directories for the working directory.
+func (a *App) templateDirs() []string {
+    cwd, _ := os.Getwd()
+    return templates.Dirs(a.configDir, cwd)
+}
+
+// renderTemplate loads and renders template name.
+func (a *App) renderTemplate(name string, vars map[string]string) (templates.Rendered, error) {
+    t, err := templates.Find(a.templateDirs(), name)
+    if err != nil {
+        return templates.Rendered{}, err
+    }
+    r, err := t.Render(vars)
+    if err != nil {
+        return r, fmt.Errorf("%w (usage: %s)", err, t.Usage())
+    }
+    logrus.WithFields(logrus.Fields{"template": name, "path": t.Path, "vars": len(vars), "attach": len(r.Attach)}).Info("rendered template")
+    return r, nil
+}
+
+// useTemplate renders template name, attaches the files it names and
+// returns the prompt to send. The files are read first and attached only if
+// all of them can be read.
+func (s *session) useTemplate(name string, vars map[string]string) (string, error) {
+    r, err := s.app.renderTemplate(name, vars)
+    if err != nil {
+        return "", err
+    }
+    contents := make([]string, len(r.Attach))
+    for i, path := range r.Attach {
+        c, truncated, err := s.app.providerRead(path, 0, 0)
+        if err != nil {
+            return "", fmt.Errorf("attach %s: %w", path, err)
+        }
+        if truncated {
+            fmt.Printf("Note: %s truncated.\n", path)
+        }
+        contents[i] = c
+    }
+    for i, path := range r.Attach {
+        path := path
+        s.attach(path, contents[i], func() (string, error) {
+            contents, _, err := s.app.providerRead(path, 0, 0)
+            return contents, err
+        })
+        fmt.Printf("Attached %s (%d chars).\n", path, len(contents[i]))
+    }
+    return r.Prompt, nil
+}
+
+// printTemplates lists the available templates for :template.
+func (a *App) printTemplates() error {
+    dirs := a.templateDirs()
+    list, err := templates.List(dirs)
+    if err != nil {
+        return err
+    }
+    if len(list) == 0 {
+        fmt.Printf("No templates. Add Markdown files to %s.\n", strings.Join(dirs, " or "))
+        return nil
+    }
+    for _, t := range list {
+        fmt.Printf("  %-40s %s\n", t.Usage(), t.Description)
+    }
+    return nil
+}
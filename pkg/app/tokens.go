@@ -0,0 +1,327 @@
+package app
+
+import (
+    "fmt"
+    "path/filepath"
+    "sort"
+    "strings"
+
+    "github.com/sirupsen/logrus"
+
+    "gg/internal/tokens"
+)
+
+// defaultTokenLimit is a conservative estimate of what ChatGPT accepts in
+// one message; override it with token_limit (or token_limit.<model>) in the
+// config file.
+const defaultTokenLimit = 32000
+
+const (
+    truncatedNote = "\n[... truncated by chatbang to fit the message limit]\n"
+    omittedNote   = "[omitted by chatbang: over the message limit]\n"
+    // noteTokens is room kept for a note and the fences around content.
+    noteTokens = 24
+)
+
+const summariseInstruction = "Summarise the following file so that later questions about it can be answered from the summary alone. Keep names, signatures, types and the key logic; drop boilerplate. Reply with the summary only."
+
+// maxAckChars is the longest answer to a part still taken as a plain
+// acknowledgement.
+const maxAckChars = 40
+
+const ackInstruction = "This is only part of the context. Reply with just \"OK\" and wait for the remaining parts and the question."
+
+func init() {
+    RegisterCommand(Command{
+        Name: "tokens",
+        Help: "estimate the tokens of the next prompt and its attachments",
+        Long: "Show the estimated tokens of each pending attachment and of the prompt composed from them and the given text, against the message limit for the current model (token_limit in the config file). Nothing is sent.",
+        Args: []ArgSpec{{Name: "prompt", Optional: true, Rest: true}},
+        Run: func(c *Context) error {
+            c.s.printTokens(c.Args.Arg(0))
+            return nil
+        },
+    })
+}
+
+// promptLimit returns the token limit for the current model: that of the
+// token_limit.<model> whose name equals the model's, else of the longest
+// one contained in it, else token_limit.
+func (a *App) promptLimit() int {
+    model := strings.ToLower(a.model)
+    if n, ok := a.modelLimits[model]; ok {
+        return n
+    }
+    limit, best := a.tokenLimit, 0
+    for name, n := range a.modelLimits {
+        if len(name) > best && model != "" && strings.Contains(model, name) {
+            limit, best = n, len(name)
+        }
+    }
+    if limit <= 0 {
+        return defaultTokenLimit
+    }
+    return limit
+}
+
+// formatTokens renders a token count compactly, e.g. "812" or "12.4k".
+func formatTokens(n int) string {
+    if n < 1000 {
+        return fmt.Sprint(n)
+    }
+    return fmt.Sprintf("%.1fk", float64(n)/1000)
+}
+
+// printTokens shows the token estimate of the next prompt for :tokens.
+func (s *session) printTokens(line string) {
+    pending := s.pendingAttachments()
+    prompt := s.compose(line)
+    total, limit := tokens.Count(prompt), s.app.promptLimit()
+    fmt.Printf("%8s  %s\n", "~tokens", "part")
+    rest := total
+    if s.contextPending() {
+        n := tokens.Count(s.project.Body)
+        rest -= n
+        fmt.Printf("%8d  project context (%s)\n", n, filepath.Base(s.project.Path))
+    }
+    for _, at := range pending {
+        n := tokens.Count(at.content)
+        rest -= n
+        fmt.Printf("%8d  %s\n", n, at.path)
+    }
+    fmt.Printf("%8d  %s\n", rest, "header, prompt and style")
+    model := s.app.model
+    if model == "" {
+        model = "the current model"
+    }
+    fmt.Printf("%8d  total, %d%% of the %d-token limit for %s\n", total, total*100/limit, limit, model)
+}
+
+// fitPrompt handles a prompt estimated over the message limit. If only the
+// attachments make it too large, they are split across messages (unless
+// auto_split=false); otherwise it asks whether to truncate, summarise or
+// split them, or to send anyway. It returns the prompt to send for line;
+// leading parts are sent here. ok is false if the turn was cancelled.
+func (s *session) fitPrompt(line string, count, limit int) (prompt string, ok bool) {
+    pending := s.pendingAttachments()
+    if s.app.autoSplit && len(pending) > 0 && tokens.Count(s.composeWith(nil, line))+2*noteTokens <= limit {
+        fmt.Printf("This prompt is ~%d tokens, over the limit of %d; sending it in parts.\n", count, limit)
+        logrus.WithFields(logrus.Fields{"tokens": count, "limit": limit}).Info("prompt over token limit; splitting")
+        return s.sendParts(s.splitMessages(pending, line, limit))
+    }
+    fmt.Printf("Warning: this prompt is ~%d tokens, over the limit of %d.\n", count, limit)
+    question := "[t]runcate, [s]ummarise, s[p]lit across messages, send [a]nyway or [c]ancel?"
+    if len(pending) == 0 {
+        question = "[t]runcate, send [a]nyway or [c]ancel?"
+    }
+    choice := s.choose(question)
+    logrus.WithFields(logrus.Fields{"tokens": count, "limit": limit, "choice": string(choice)}).Info("prompt over token limit")
+    switch {
+    case choice == 'a':
+        return s.compose(line), true
+    case choice == 't':
+        atts, line := s.truncateToFit(pending, line, limit)
+        return s.composeWith(atts, line), true
+    case choice == 's' && len(pending) > 0:
+        atts, line, err := s.summariseToFit(pending, line, limit)
+        if err != nil {
+            fmt.Printf("chat error: %v\n", err)
+            return "", false
+        }
+        return s.composeWith(atts, line), true
+    case choice == 'p' && len(pending) > 0:
+        return s.sendParts(s.splitMessages(pending, line, limit))
+    }
+    fmt.Println("Cancelled; attachments are kept for the next prompt.")
+    return "", false
+}
+
+// sendParts sends every message but the last, each of which asks only for
+// an acknowledgement, and returns the last one, which carries the question.
+// The acknowledgements are logged but not shown.
+func (s *session) sendParts(messages []string) (string, bool) {
+    n := len(messages)
+    for i, m := range messages[:n-1] {
+        fmt.Printf("[Sending part %d of %d...]\n", i+1, n)
+        ack, err := s.app.ask(m)
+        if err != nil {
+            fmt.Printf("chat error: %v\n", err)
+            return "", false
+        }
+        ack = strings.TrimSpace(ack)
+        entry := logrus.WithFields(logrus.Fields{"part": i + 1, "parts": n, "tokens": tokens.Count(m), "ack_chars": len(ack)})
+        if len(ack) > maxAckChars {
+            // ChatGPT answered instead of just acknowledging; carry on,
+            // the question comes with the final part.
+            entry.Warn("part answered with more than an acknowledgement")
+        } else {
+            entry.Debug("part acknowledged")
+        }
+    }
+    fmt.Printf("[Sending part %d of %d with the question...]\n", n, n)
+    return messages[n-1], true
+}
+
+// truncateToFit returns copies of atts cut so that the prompt fits in
+// limit. Attachments that do not fit are cut or, once the room runs out,
+// replaced by a note. The line itself is cut only if it does not fit on its
+// own.
+func (s *session) truncateToFit(atts []attachment, line string, limit int) ([]attachment, string) {
+    out := make([]attachment, len(atts))
+    empty := make([]attachment, len(atts))
+    for i, at := range atts {
+        out[i] = at
+        empty[i] = attachment{path: at.path}
+    }
+    room := limit - tokens.Count(s.composeWith(empty, line)) - noteTokens*len(atts)
+    if room < 0 {
+        fixed := tokens.Count(s.composeWith(empty, "")) + noteTokens*(len(atts)+1)
+        line, _ = tokens.Truncate(line, max(limit-fixed, 0))
+        line += truncatedNote
+        fmt.Println("Truncated the prompt itself.")
+        room = 0
+    }
+    // Keep whole what fits; cut the first attachment that does not and
+    // leave out the rest.
+    var over []int
+    for i, at := range out {
+        if n := tokens.Count(at.content); n <= room {
+            room -= n
+        } else {
+            over = append(over, i)
+        }
+    }
+    for _, i := range over {
+        at := &out[i]
+        n := tokens.Count(at.content)
+        if room > noteTokens {
+            at.content, _ = tokens.Truncate(at.content, room)
+            at.content += truncatedNote
+            fmt.Printf("Truncated %s to ~%d of %d tokens.\n", at.path, room, n)
+            room = 0
+            continue
+        }
+        at.content = omittedNote
+        fmt.Printf("Omitted %s (~%d tokens).\n", at.path, n)
+    }
+    return out, line
+}
+
+// summariseToFit replaces the largest attachments, one at a time, by
+// summaries ChatGPT writes for them until the prompt fits in limit. Whatever
+// is still over is truncated.
+func (s *session) summariseToFit(atts []attachment, line string, limit int) ([]attachment, string, error) {
+    out := append([]attachment(nil), atts...)
+    order := make([]int, len(out))
+    for i := range order {
+        order[i] = i
+    }
+    sort.SliceStable(order, func(i, j int) bool {
+        return len(out[order[i]].content) > len(out[order[j]].content)
+    })
+    for _, i := range order {
+        if tokens.Count(s.composeWith(out, line)) <= limit {
+            return out, line, nil
+        }
+        summary, err := s.summarise(out[i], limit)
+        if err != nil {
+            return nil, "", err
+        }
+        fmt.Printf("Summarised %s from ~%d to ~%d tokens.\n", out[i].path, tokens.Count(out[i].content), tokens.Count(summary))
+        out[i].path += " (summary)"
+        out[i].content = summary
+    }
+    if tokens.Count(s.composeWith(out, line)) <= limit {
+        return out, line, nil
+    }
+    out, line = s.truncateToFit(out, line, limit)
+    return out, line, nil
+}
+
+// summarise asks ChatGPT for a summary of at, sending it in parts if it does
+// not fit in one message.
+func (s *session) summarise(at attachment, limit int) (string, error) {
+    room := limit - tokens.Count(summariseInstruction+at.path) - 2*noteTokens
+    parts := tokens.Split(at.content, max(room, noteTokens))
+    var summaries []string
+    for i, part := range parts {
+        label := at.path
+        if len(parts) > 1 {
+            label = fmt.Sprintf("%s (part %d of %d)", at.path, i+1, len(parts))
+        }
+        fmt.Printf("[Summarising %s...]\n", label)
+        var b strings.Builder
+        b.WriteString(summariseInstruction)
+        b.WriteString("\n\n")
+        writeAttachment(&b, attachment{path: label, content: part})
+        answer, err := s.app.ask(strings.TrimSpace(b.String()))
+        if err != nil {
+            return "", err
+        }
+        logrus.WithFields(logrus.Fields{"path": label, "tokens": tokens.Count(part), "summary_tokens": tokens.Count(answer)}).Info("summarised attachment")
+        summaries = append(summaries, strings.TrimSpace(answer))
+    }
+    return strings.Join(summaries, "\n\n"), nil
+}
+
+// splitMessages spreads atts over messages that each fit in limit.
+// Attachments too large for one message are cut into labelled pieces. Every
+// message but the last asks only for an acknowledgement; the last carries
+// line.
+func (s *session) splitMessages(atts []attachment, line string, limit int) []string {
+    header := s.attachHeader()
+    room := limit - tokens.Count(fmt.Sprintf("Part 99 of 99. %s\n\n\n%s", header, ackInstruction)) - noteTokens
+    room = max(room, noteTokens)
+    var blocks []attachment
+    for _, at := range atts {
+        pieces := tokens.Split(at.content, room-tokens.Count(at.path)-noteTokens)
+        if len(pieces) <= 1 {
+            blocks = append(blocks, at)
+            continue
+        }
+        for i, p := range pieces {
+            blocks = append(blocks, attachment{path: fmt.Sprintf("%s (piece %d of %d)", at.path, i+1, len(pieces)), content: p})
+        }
+    }
+    var groups [][]attachment
+    used := 0
+    for _, b := range blocks {
+        n := tokens.Count(b.path+b.content) + noteTokens
+        if len(groups) == 0 || used+n > room {
+            groups = append(groups, nil)
+            used = 0
+        }
+        groups[len(groups)-1] = append(groups[len(groups)-1], b)
+        used += n
+    }
+    // The question, and the project context if it is pending, go with the
+    // last group if they fit, else on their own.
+    tail := tokens.Count(s.composeWith(nil, line)) + noteTokens
+    if used+tail > room {
+        groups = append(groups, nil)
+    }
+    messages := make([]string, len(groups))
+    for i, g := range groups {
+        var b strings.Builder
+        if i == len(groups)-1 {
+            fmt.Fprintf(&b, "Part %d of %d, the last. Answer using all parts. ", i+1, len(groups))
+            if len(g) == 0 {
+                b.WriteString("All context has been sent; here is the question.\n\n")
+                b.WriteString(s.composeWith(nil, line))
+            } else {
+                b.WriteString(s.composeWith(g, line))
+            }
+        } else {
+            fmt.Fprintf(&b, "Part %d of %d. ", i+1, len(groups))
+            b.WriteString(header)
+            b.WriteString("\n\n")
+            for _, at := range g {
+                writeAttachment(&b, at)
+            }
+            b.WriteString(ackInstruction)
+        }
+        messages[i] = b.String()
+    }
+    logrus.WithFields(logrus.Fields{"parts": len(messages), "attachments": len(atts), "limit": limit}).Info("split prompt across messages")
+    return messages
+}